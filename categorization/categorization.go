@@ -0,0 +1,76 @@
+// Package categorization tracks the category web filtering vendors give a domain.
+package categorization
+
+import (
+	"strings"
+	"time"
+)
+
+// Vendors tracked for every domain, a domain is uncategorised at the vendors without a category
+var Vendors = []string{
+	"Barracuda",
+	"BitDefender",
+	"Check Point",
+	"Cisco Talos",
+	"Cloudflare",
+	"Forcepoint",
+	"McAfee",
+	"Norton",
+	"Palo Alto",
+	"SonicWall",
+	"Sophos",
+	"Symantec",
+	"Trellix",
+	"Trend Micro",
+	"Zscaler",
+}
+
+// Names VirusTotal uses for the tracked vendors, other vendors keep the VirusTotal name
+var aliases = map[string]string{
+	"forcepoint threatseeker": "Forcepoint",
+}
+
+// Categories vendors report for a domain they have not categorised yet
+var uncategorized = []string{
+	"",
+	"none",
+	"not rated",
+	"uncategorised",
+	"uncategorized",
+	"unknown",
+	"unrated",
+	"unclassified",
+}
+
+// Entry of the submission log of a categorization, saved when a recategorisation is requested
+type Submission struct {
+	User     string    `json:"user"`
+	Date     time.Time `json:"date"`
+	Category string    `json:"category"`
+}
+
+// Normalize a vendor name from another source to the name of the tracked vendor. Unknown
+// vendors are returned as they are.
+func Normalize(vendor string) string {
+	vendor = strings.TrimSpace(vendor)
+	if name, ok := aliases[strings.ToLower(vendor)]; ok {
+		return name
+	}
+	for _, name := range Vendors {
+		if strings.EqualFold(name, vendor) {
+			return name
+		}
+	}
+	return vendor
+}
+
+// IsUncategorized reports if a category means the vendor has not categorised the domain
+func IsUncategorized(category string) bool {
+	category = strings.ToLower(strings.TrimSpace(category))
+	for _, c := range uncategorized {
+		if category == c {
+			return true
+		}
+	}
+	return false
+}
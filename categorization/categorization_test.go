@@ -0,0 +1,30 @@
+package categorization
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Forcepoint ThreatSeeker": "Forcepoint",
+		"bitdefender":             "BitDefender",
+		" Sophos ":                "Sophos",
+		"alphaMountain.ai":        "alphaMountain.ai",
+	}
+	for vendor, want := range tests {
+		if got := Normalize(vendor); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", vendor, got, want)
+		}
+	}
+}
+
+func TestIsUncategorized(t *testing.T) {
+	for _, category := range []string{"", "Uncategorized", "not rated", " Unknown "} {
+		if !IsUncategorized(category) {
+			t.Errorf("IsUncategorized(%q) = false", category)
+		}
+	}
+	for _, category := range []string{"information technology", "Business and Economy"} {
+		if IsUncategorized(category) {
+			t.Errorf("IsUncategorized(%q) = true", category)
+		}
+	}
+}
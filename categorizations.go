@@ -0,0 +1,87 @@
+package main
+
+import (
+	"time"
+
+	"github.com/lum8rjack/redcompass/categorization"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Source of a category entered on the Categorizations page
+const CategorySourceManual = "manual"
+
+// Find the categorization of a domain at a vendor, a new record is returned when the vendor
+// was never checked or submitted to
+func findCategorization(domainID string, vendor string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByFilter("Categorizations",
+		"Domain = {:domain} && Vendor = {:vendor}",
+		dbx.Params{"domain": domainID, "vendor": vendor},
+	)
+	if err == nil {
+		return record, nil
+	}
+
+	collection, err := app.FindCollectionByNameOrId("Categorizations")
+	if err != nil {
+		return nil, err
+	}
+	record = core.NewRecord(collection)
+	record.Set("Domain", domainID)
+	record.Set("Vendor", vendor)
+	return record, nil
+}
+
+// Save the category a vendor gave a domain and when it was checked. The previous category is
+// returned, it is empty when the vendor was not checked before.
+func SetCategorization(domainID string, vendor string, category string, source string, checked time.Time) (string, error) {
+	record, err := findCategorization(domainID, categorization.Normalize(vendor))
+	if err != nil {
+		return "", err
+	}
+
+	previous := record.GetString("Category")
+	record.Set("Category", category)
+	record.Set("Source", source)
+	record.Set("Last_Checked", checked)
+
+	return previous, app.Save(record)
+}
+
+// Add a recategorisation request to the submission log of a domain at a vendor
+func AddCategorizationSubmission(domainID string, vendor string, submission categorization.Submission) (*core.Record, error) {
+	record, err := findCategorization(domainID, categorization.Normalize(vendor))
+	if err != nil {
+		return nil, err
+	}
+
+	var submissions []categorization.Submission
+	if err := record.UnmarshalJSONField("Submissions", &submissions); err != nil {
+		return nil, err
+	}
+	submissions = append(submissions, submission)
+
+	record.Set("Submissions", submissions)
+	record.Set("Last_Submitted", submission.Date)
+	return record, app.Save(record)
+}
+
+// Save the vendor categories a scanner reported for a domain, the scanner is saved as the source
+func AddVirusTotalCategorizations(scanner string, domainName string, categories map[string]string) error {
+	if len(categories) == 0 {
+		return nil
+	}
+
+	domain, err := app.FindFirstRecordByData("Domains", "Name", domainName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for vendor, category := range categories {
+		if _, err := SetCategorization(domain.Id, vendor, category, scanner, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"time"
 
+	"github.com/lum8rjack/redcompass/providers"
 	"github.com/lum8rjack/redcompass/scanners"
-	"github.com/lum8rjack/redcompass/scanners/virustotal"
 	"github.com/lum8rjack/redcompass/services"
+	"github.com/lum8rjack/redcompass/services/types"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 )
@@ -26,73 +28,146 @@ func AddDomainsCronJob(record *core.Record) error {
 		return errors.New("cron is empty")
 	}
 
-	jobID := record.GetString("Provider")
+	// Use a copy of the record since the API response masks the settings on the original
+	record = record.Fresh()
+
+	// Each service record has its own job so multiple accounts for the same provider can be used
+	jobID := record.Id
 	cron := record.GetString("Cron")
 
 	app.Cron().MustAdd(jobID, cron, func() {
-		msg := "CRON:" + jobID + " cron job"
-		app.Logger().Info(msg, "status", "started")
-
-		// Get the service client
-		service, err := services.NewService(jobID, record.GetString("Settings"))
-		if err != nil {
-			app.Logger().Error(msg, "function", "services.NewService", "error", err.Error())
+		// Skip the run if a manual or previous run is still in progress
+		unlock, ok := lockService(jobID)
+		if !ok {
+			app.Logger().Warn("CRON:"+serviceLogName(record)+" cron job", "status", "skipped", "note", "a previous run is still in progress")
 			return
 		}
+		defer unlock()
+
+		SyncServiceDomains(jobsCtx, record, TriggerScheduled)
+	})
+
+	return nil
+}
+
+// Sync the domains and DNS records for a registrar service. The caller must hold the service lock.
+func SyncServiceDomains(ctx context.Context, record *core.Record, trigger string) {
+	msg := "CRON:" + serviceLogName(record) + " " + trigger + " sync"
+	app.Logger().Info(msg, "status", "started")
+	provider := record.GetString("Provider")
+
+	// Record the run in Job_Runs
+	run := StartJobRun(record, JobSync, trigger)
+	defer run.Finish()
+
+	// Get the service client
+	settings, err := GetServiceSettings(record)
+	if err != nil {
+		app.Logger().Error(msg, "function", "GetServiceSettings", "error", err.Error())
+		run.Fail("GetServiceSettings", err)
+		return
+	}
+	service, err := services.NewService(provider, settings)
+	if err != nil {
+		app.Logger().Error(msg, "function", "services.NewService", "error", err.Error())
+		run.Fail("services.NewService", err)
+		return
+	}
+
+	// Get the domains, the service is disabled if the provider rejects the credentials
+	var domains []types.Domain
+	err = retryProviderCall(ctx, msg, func() error {
+		domains, err = service.GetDomains(ctx)
+		return err
+	})
+	if err != nil {
+		app.Logger().Error(msg, "function", "service.GetDomains", "error", err.Error())
+		run.Fail("service.GetDomains", err)
+		if errors.Is(err, providers.ErrAuth) {
+			disableService(record, err.Error())
+		}
+		return
+	}
 
-		// Get the domains
-		domains, err := service.GetDomains()
+	// Loop through the domains
+	ingested := 0
+	for _, d := range domains {
+		// Stop if the app is shutting down
+		if ctx.Err() != nil {
+			app.Logger().Warn(msg, "status", "cancelled", "error", ctx.Err().Error())
+			run.Fail("context", ctx.Err())
+			break
+		}
+
+		err = AddDomain(record, d.Name, d.Created, d.Expires, d.IsExpired, d.AutoRenew, d.IsLocked, !d.IsOurDNS)
 		if err != nil {
-			app.Logger().Error(msg, "function", "service.GetDomains", "error", err.Error())
-			return
+			app.Logger().Error(msg, "function", "AddDomainRecord", "domain", d.Name, "error", err.Error())
+			run.AddError(d.Name, "AddDomain", err)
+			continue
 		}
+		ingested++
 
-		// Loop through the domains
-		for _, d := range domains {
-			err = AddDomain(jobID, d.Name, d.Created, d.Expires, d.IsExpired, d.AutoRenew, d.IsLocked, !d.IsOurDNS)
+		// Get records for each domain if:
+		// - The domain is using our DNS
+		// - The domain is not expired
+		// - The domain is not locked
+		// Otherwise the domain is synced with no records so any stale records are removed
+		var domainRecords []types.Record
+		if d.IsOurDNS && !d.IsExpired && !d.IsLocked {
+			err = retryProviderCall(ctx, msg, func() error {
+				domainRecords, err = service.GetDomainRecords(ctx, d.Name)
+				return err
+			})
 			if err != nil {
-				app.Logger().Error(msg, "function", "AddDomainRecord", "domain", d.Name, "error", err.Error())
+				app.Logger().Error(msg, "function", "GetDomainRecords", "domain", d.Name, "error", err.Error())
+				run.AddError(d.Name, "service.GetDomainRecords", err)
 				continue
 			}
+		} else {
+			app.Logger().Debug(msg+" skipped record retrieval", "status", "skipped", "domain", d.Name, "isOurDNS", d.IsOurDNS, "isExpired", d.IsExpired, "isLocked", d.IsLocked)
+		}
 
-			// Delete all existing records for this domain
-			err = DeleteAllDomainRecords(d.Name)
-			if err != nil {
-				app.Logger().Error(msg, "function", "DeleteAllDomainRecords", "domain", d.Name, "error", err.Error())
-				continue
-			}
+		// Only apply the records that changed
+		changes, err := SyncDomainRecords(provider, d.Name, domainRecords)
+		if err != nil {
+			app.Logger().Error(msg, "function", "SyncDomainRecords", "domain", d.Name, "error", err.Error())
+			run.AddError(d.Name, "SyncDomainRecords", err)
+			continue
+		}
+		if len(changes) > 0 {
+			app.Logger().Debug(msg+" records changed", "domain", d.Name, "changes", len(changes))
+		}
+		run.RecordsChanged += len(changes)
+	}
+	run.DomainsProcessed = ingested
 
-			// Get records for each domain if:
-			// - The domain is using our DNS
-			// - The domain is not expired
-			// - The domain is not locked
-			if d.IsOurDNS && !d.IsExpired && !d.IsLocked {
-				domainRecords, err := service.GetDomainRecords(d.Name)
-				if err != nil {
-					app.Logger().Error(msg, "function", "GetDomainRecords", "domain", d.Name, "error", err.Error())
-					continue
-				}
+	// Log a summary so a truncated sync is obvious
+	total := len(domains)
+	if t, ok := service.(types.DomainTotaler); ok && t.GetTotalDomains() > 0 {
+		total = t.GetTotalDomains()
+	}
 
-				// Add new records
-				for _, r := range domainRecords {
-					err = AddDomainRecord(d.Name, r.Name, r.Type, r.Address)
-					if err != nil {
-						app.Logger().Error(msg, "function", "AddDomainRecord", "domain", d.Name, "error", err.Error())
-						continue
-					}
-				}
-			} else {
-				app.Logger().Debug(msg+" skipped record retrieval", "status", "skipped", "domain", d.Name, "isOurDNS", d.IsOurDNS, "isExpired", d.IsExpired, "isLocked", d.IsLocked)
-			}
+	// Flag the domains that are no longer at the provider, only when the full list was received
+	if ctx.Err() == nil && len(domains) >= total {
+		removed, err := FlagRemovedDomains(record, domains)
+		if err != nil {
+			app.Logger().Error(msg, "function", "FlagRemovedDomains", "error", err.Error())
+			run.AddError("", "FlagRemovedDomains", err)
 		}
-		app.Logger().Info(msg, "status", "completed")
-	})
-
-	return nil
+		for _, name := range removed {
+			app.Logger().Warn(msg+" domain removed from provider", "domain", name)
+		}
+	}
+	if ingested < total {
+		app.Logger().Warn(msg+" sync summary", "status", "incomplete", "total", total, "received", len(domains), "ingested", ingested)
+	} else {
+		app.Logger().Info(msg+" sync summary", "status", "complete", "total", total, "received", len(domains), "ingested", ingested)
+	}
+	app.Logger().Info(msg, "status", "completed")
 }
 
-// Add a cron job for VirusTotal domain reputation scanning
-func AddVirusTotalCronJob(record *core.Record) error {
+// Add a cron job for domain reputation scanning with a scanner service
+func AddScannerCronJob(record *core.Record) error {
 	// Check if the record has a provider, settings, and cron
 	if record.GetString("Provider") == "" {
 		return errors.New("provider is empty")
@@ -106,80 +181,345 @@ func AddVirusTotalCronJob(record *core.Record) error {
 		return errors.New("cron is empty")
 	}
 
-	jobID := record.GetString("Provider")
+	// Use a copy of the record since the API response masks the settings on the original
+	record = record.Fresh()
+	jobID := record.Id
 	cron := record.GetString("Cron")
 
 	app.Cron().MustAdd(jobID, cron, func() {
-		msg := "CRON:" + jobID + " cron job"
-		app.Logger().Info(msg, "status", "started")
-
-		// Get the scanner client
-		scanner, err := scanners.NewScanner(jobID, record.GetString("Settings"))
-		if err != nil {
-			app.Logger().Error(msg, "function", "scanners.NewScanner", "error", err.Error())
+		// Skip the run if a manual or previous run is still in progress
+		unlock, ok := lockService(jobID)
+		if !ok {
+			app.Logger().Warn("CRON:"+serviceLogName(record)+" cron job", "status", "skipped", "note", "a previous run is still in progress")
 			return
 		}
+		defer unlock()
 
-		// Check if the API key is valid
-		if !scanner.IsKeyValid() {
-			app.Logger().Error(msg, "function", "scanner.IsKeyValid", "error", "API key is invalid")
-			return
+		ScanDomains(jobsCtx, record, TriggerScheduled, "")
+	})
+
+	return nil
+}
+
+// Scan the domains with a scanner service, only the given domain is scanned when domainID is
+// not empty. The caller must hold the service lock.
+func ScanDomains(ctx context.Context, record *core.Record, trigger string, domainID string) {
+	msg := "CRON:" + serviceLogName(record) + " " + trigger + " scan"
+	app.Logger().Info(msg, "status", "started")
+	provider := record.GetString("Provider")
+
+	// Record the run in Job_Runs
+	run := StartJobRun(record, JobScan, trigger)
+	defer run.Finish()
+
+	// Get the scanner client
+	settings, err := GetServiceSettings(record)
+	if err != nil {
+		app.Logger().Error(msg, "function", "GetServiceSettings", "error", err.Error())
+		run.Fail("GetServiceSettings", err)
+		return
+	}
+	scanner, err := scanners.NewScanner(provider, settings)
+	if err != nil {
+		app.Logger().Error(msg, "function", "scanners.NewScanner", "error", err.Error())
+		run.Fail("scanners.NewScanner", err)
+		return
+	}
+
+	// Check if the API key is valid, the service is disabled if the provider rejects it
+	err = retryProviderCall(ctx, msg, func() error {
+		return scanner.ValidateKey(ctx)
+	})
+	if err != nil {
+		app.Logger().Error(msg, "function", "scanner.ValidateKey", "error", err.Error())
+		run.Fail("scanner.ValidateKey", err)
+		if errors.Is(err, providers.ErrAuth) {
+			disableService(record, err.Error())
 		}
+		return
+	}
+
+	// Get the domains from the database
+	filters := []dbx.Expression{
+		dbx.NewExp("Is_Expired = {:isExpired}", dbx.Params{"isExpired": false}),
+		dbx.NewExp("Is_Locked = {:isLocked}", dbx.Params{"isLocked": false}),
+		dbx.NewExp("Removed_From_Provider = {:removed}", dbx.Params{"removed": false}),
+	}
+	if domainID != "" {
+		filters = append(filters, dbx.HashExp{"id": domainID})
+	}
+	domains, err := app.FindAllRecords("Domains", filters...)
+	if err != nil {
+		app.Logger().Error(msg, "function", "app.FindAllRecords that are not expired, locked or removed", "error", err.Error())
+		run.Fail("app.FindAllRecords", err)
+		return
+	}
+
+	var quotaRemaining int
+	err = retryProviderCall(ctx, msg, func() error {
+		quotaRemaining, err = scanner.GetDailyQuotaRemaining(ctx)
+		return err
+	})
+	if err != nil {
+		app.Logger().Error(msg, "function", "scanner.GetDailyQuotaRemaining", "error", err.Error())
+		run.Fail("scanner.GetDailyQuotaRemaining", err)
+		return
+	}
+
+	app.Logger().Info(msg, "function", "scanner.GetDailyQuotaRemaining", "quotaRemaining", quotaRemaining)
 
-		// Get the domains from the database
-		domains, err := app.FindAllRecords("Domains",
-			dbx.NewExp("Is_Expired = {:isExpired}", dbx.Params{"isExpired": false}),
-			dbx.NewExp("Is_Locked = {:isLocked}", dbx.Params{"isLocked": false}),
-		)
+	// A single domain scan was asked for, so it fails when the budget is used up. A scheduled
+	// scan carries all domains over to the next run instead.
+	if quotaRemaining <= 0 && domainID != "" {
+		app.Logger().Error(msg, "function", "scanner.GetDailyQuotaRemaining", "error", "no quota remaining")
+		run.Fail("scanner.GetDailyQuotaRemaining", providers.NewError(provider, providers.ErrQuotaExhausted, "no quota remaining"))
+		return
+	}
+
+	// Scan the domains with the highest priority that fit in the remaining budget, the others
+	// are carried over to the next run
+	if len(domains) > quotaRemaining {
+		domains, err = prioritizeScanDomains(provider, domains)
 		if err != nil {
-			app.Logger().Error(msg, "function", "app.FindAllRecords that are not expired or locked", "error", err.Error())
+			app.Logger().Error(msg, "function", "prioritizeScanDomains", "error", err.Error())
+			run.Fail("prioritizeScanDomains", err)
 			return
 		}
 
-		quotaRemaining, err := scanner.GetDailyQuotaRemaining()
+		run.DomainsSkipped = len(domains) - max(quotaRemaining, 0)
+		domains = domains[:max(quotaRemaining, 0)]
+		app.Logger().Info(msg, "function", "prioritizeScanDomains", "domainsScanned", len(domains), "domainsCarriedOver", run.DomainsSkipped)
+	}
+
+	// Loop through the domains
+	for _, d := range domains {
+		// Stop if the app is shutting down
+		if ctx.Err() != nil {
+			app.Logger().Warn(msg, "status", "cancelled", "error", ctx.Err().Error())
+			run.Fail("context", ctx.Err())
+			break
+		}
+
+		domainName := d.GetString("Name")
+
+		// Get the results for the domain, the remaining domains are skipped if the provider
+		// keeps rate limiting the requests or the quota is used up
+		var results []byte
+		err = retryProviderCall(ctx, msg, func() error {
+			results, err = scanner.GetResults(ctx, domainName)
+			return err
+		})
 		if err != nil {
-			app.Logger().Error(msg, "function", "scanner.GetDailyQuotaRemaining", "error", err.Error())
-			return
+			app.Logger().Error(msg, "function", "scanner.GetResults", "domain", domainName, "error", err.Error())
+			if providers.IsRetryable(err) || errors.Is(err, providers.ErrQuotaExhausted) || errors.Is(err, providers.ErrAuth) {
+				run.Fail("scanner.GetResults", err)
+				break
+			}
+			run.AddError(domainName, "scanner.GetResults", err)
+			continue
+		}
+		function, err := saveScanResults(provider, results)
+		if err != nil {
+			app.Logger().Error(msg, "function", function, "domain", domainName, "error", err.Error())
+			run.AddError(domainName, function, err)
+			continue
 		}
+		run.DomainsProcessed++
+	}
+	app.Logger().Info(msg, "status", "completed")
+}
+
+// Add a cron job for categorisation checks with a checker service
+func AddCheckerCronJob(record *core.Record) error {
+	// Check if the record has a provider and cron, checkers of public lookup pages have no settings
+	if record.GetString("Provider") == "" {
+		return errors.New("provider is empty")
+	}
 
-		app.Logger().Info(msg, "function", "scanner.GetDailyQuotaRemaining", "quotaRemaining", quotaRemaining)
+	if record.GetString("Cron") == "" {
+		return errors.New("cron is empty")
+	}
+
+	// Use a copy of the record since the API response masks the settings on the original
+	record = record.Fresh()
+	jobID := record.Id
+	cron := record.GetString("Cron")
 
-		if quotaRemaining <= 0 {
-			app.Logger().Error(msg, "function", "scanner.GetDailyQuotaRemaining", "error", "no quota remaining")
+	app.Cron().MustAdd(jobID, cron, func() {
+		// Skip the run if a manual or previous run is still in progress
+		unlock, ok := lockService(jobID)
+		if !ok {
+			app.Logger().Warn("CRON:"+serviceLogName(record)+" cron job", "status", "skipped", "note", "a previous run is still in progress")
 			return
 		}
+		defer unlock()
 
-		if len(domains) > quotaRemaining {
-			app.Logger().Error(msg, "function", "scanner.GetDailyQuotaRemaining", "error", "not enough quota remaining")
-			return
+		CheckCategories(jobsCtx, record, TriggerScheduled)
+	})
+
+	return nil
+}
+
+// Check the category of the domains with a checker service and save it to Categorizations. A
+// notification is sent when the vendor changed the category of a domain since the last check.
+// The caller must hold the service lock.
+func CheckCategories(ctx context.Context, record *core.Record, trigger string) {
+	msg := "CRON:" + serviceLogName(record) + " " + trigger + " check"
+	app.Logger().Info(msg, "status", "started")
+	provider := record.GetString("Provider")
+
+	// Record the run in Job_Runs
+	run := StartJobRun(record, JobCheck, trigger)
+	defer run.Finish()
+
+	// Get the checker client
+	settings, err := GetServiceSettings(record)
+	if err != nil {
+		app.Logger().Error(msg, "function", "GetServiceSettings", "error", err.Error())
+		run.Fail("GetServiceSettings", err)
+		return
+	}
+	checker, err := scanners.NewChecker(provider, settings)
+	if err != nil {
+		app.Logger().Error(msg, "function", "scanners.NewChecker", "error", err.Error())
+		run.Fail("scanners.NewChecker", err)
+		return
+	}
+
+	// Get the domains from the database, expired domains are not categorised anymore
+	domains, err := app.FindAllRecords("Domains",
+		dbx.NewExp("Is_Expired = {:isExpired}", dbx.Params{"isExpired": false}),
+		dbx.NewExp("Removed_From_Provider = {:removed}", dbx.Params{"removed": false}),
+	)
+	if err != nil {
+		app.Logger().Error(msg, "function", "app.FindAllRecords that are not expired or removed", "error", err.Error())
+		run.Fail("app.FindAllRecords", err)
+		return
+	}
+
+	// Loop through the domains
+	vendor := checker.GetName()
+	for _, d := range domains {
+		// Stop if the app is shutting down
+		if ctx.Err() != nil {
+			app.Logger().Warn(msg, "status", "cancelled", "error", ctx.Err().Error())
+			run.Fail("context", ctx.Err())
+			break
 		}
 
-		// Loop through the domains
-		for _, d := range domains {
-			domainName := d.GetString("Name")
+		domainName := d.GetString("Name")
 
-			// Get the results for the domain
-			results, err := scanner.GetResults(domainName)
-			if err != nil {
-				app.Logger().Error(msg, "function", "scanner.GetResults", "domain", domainName, "error", err.Error())
-				continue
-			}
-			vtresults := virustotal.VT_Database{}
-			err = json.Unmarshal(results, &vtresults)
-			if err != nil {
-				app.Logger().Error(msg, "function", "json.Unmarshal", "domain", domainName, "error", err.Error())
-				continue
-			}
-			err = AddVirusTotalRecord(vtresults)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddVirusTotalRecord", "domain", domainName, "error", err.Error())
-				continue
+		// Get the category, the remaining domains are skipped if the vendor keeps rate limiting
+		// the requests or rejects the credentials
+		var category string
+		err = retryProviderCall(ctx, msg, func() error {
+			category, err = checker.GetCategory(ctx, domainName)
+			return err
+		})
+		if err != nil {
+			app.Logger().Error(msg, "function", "checker.GetCategory", "domain", domainName, "error", err.Error())
+			if providers.IsRetryable(err) || errors.Is(err, providers.ErrAuth) {
+				run.Fail("checker.GetCategory", err)
+				if errors.Is(err, providers.ErrAuth) {
+					disableService(record, err.Error())
+				}
+				break
 			}
+			run.AddError(domainName, "checker.GetCategory", err)
+			continue
 		}
-		app.Logger().Info(msg, "status", "completed")
-	})
 
-	return nil
+		previous, err := SetCategorization(d.Id, vendor, category, provider, time.Now().UTC())
+		if err != nil {
+			app.Logger().Error(msg, "function", "SetCategorization", "domain", domainName, "error", err.Error())
+			run.AddError(domainName, "SetCategorization", err)
+			continue
+		}
+		if previous != "" && previous != category {
+			app.Logger().Info(msg+" category changed", "domain", domainName, "vendor", vendor, "previous", previous, "category", category)
+			notifyCategoryChanged(d, vendor, previous, category)
+		}
+		run.DomainsProcessed++
+	}
+	app.Logger().Info(msg, "status", "completed")
+}
+
+// Save the results of a scanner with its store and update the Healthy flag of the domain. The
+// name of the function that failed is returned with the error.
+func saveScanResults(provider string, results []byte) (string, error) {
+	domain, err := scannerStore(provider).SaveResults(app, results)
+	if err != nil {
+		return "SaveResults", err
+	}
+
+	if err := EvaluateDomainHealth(domain); err != nil {
+		app.Logger().Error("HEALTH:"+domain, "function", "EvaluateDomainHealth", "error", err.Error())
+	}
+	return "", nil
+}
+
+// Number of times a provider call is attempted when the provider asks to wait longer than
+// the HTTP client retries for
+const maxProviderAttempts = 3
+
+// Longest time to wait before retrying a provider call
+const maxProviderRetryWait = 5 * time.Minute
+
+// Call a provider and retry when it is rate limited with a Retry-After longer than the HTTP
+// client waits for. Temporary failures and shorter waits are already retried by the client,
+// so they are returned as they are.
+func retryProviderCall(ctx context.Context, msg string, call func() error) error {
+	var err error
+	for attempt := 1; attempt <= maxProviderAttempts; attempt++ {
+		err = call()
+		if err == nil || !errors.Is(err, providers.ErrRateLimited) || attempt == maxProviderAttempts {
+			return err
+		}
+
+		wait := providers.RetryAfter(err)
+		if wait <= providers.DefaultMaxRetryWait || wait > maxProviderRetryWait {
+			return err
+		}
+
+		app.Logger().Warn(msg, "status", "retrying", "attempt", attempt, "wait", wait.String(), "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return err
+}
+
+// Disable a service after the provider rejected its credentials. The cron job is removed by the
+// update hook and the service stays disabled until its settings are saved again.
+func disableService(record *core.Record, reason string) {
+	msg := "CRON:" + serviceLogName(record) + " disable service"
+
+	service, err := app.FindRecordById("Services", record.Id)
+	if err != nil {
+		app.Logger().Error(msg, "function", "app.FindRecordById", "error", err.Error())
+		return
+	}
+
+	service.Set("Disabled", true)
+	service.Set("Disabled_Reason", reason)
+	if err := app.Save(service); err != nil {
+		app.Logger().Error(msg, "function", "app.Save", "error", err.Error())
+		return
+	}
+
+	app.Logger().Warn(msg, "status", "disabled", "reason", reason)
+}
+
+// Name of a service used in log messages, including the account label if there is one
+func serviceLogName(record *core.Record) string {
+	name := record.GetString("Provider")
+	if label := record.GetString("Label"); label != "" {
+		name += " (" + label + ")"
+	}
+	return name
 }
 
 // Remove a cron job
@@ -0,0 +1,262 @@
+// Package emailauth checks the email authentication records of a domain: SPF, DMARC, MX and
+// optionally DKIM, and grades them so misconfigured email domains are found before a campaign.
+package emailauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Grade of a check or a domain, the grade of a domain is the worst grade of its checks
+type Grade string
+
+const (
+	GradePass Grade = "pass"
+	GradeWarn Grade = "warn"
+	GradeFail Grade = "fail"
+)
+
+// Names of the checks
+const (
+	CheckSPF   = "SPF"
+	CheckDMARC = "DMARC"
+	CheckMX    = "MX"
+	CheckDKIM  = "DKIM"
+)
+
+// Resolver used for the lookups, *net.Resolver implements it
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Result of a check with the record that was checked and why it did not pass
+type Check struct {
+	Name     string   `json:"name"`
+	Grade    Grade    `json:"grade"`
+	Record   string   `json:"record"`
+	Messages []string `json:"messages"`
+}
+
+// Results of the checks of a domain
+type Results struct {
+	Domain string `json:"domain"`
+	Grade  Grade  `json:"grade"`
+
+	// DNS lookups needed to evaluate the SPF record, at most 10 are allowed
+	SPFLookups int `json:"spfLookups"`
+
+	// Policy of the DMARC record, e.g. reject
+	DMARCPolicy string `json:"dmarcPolicy"`
+
+	// Mail servers of the domain ordered by preference
+	MX []string `json:"mx"`
+
+	Checks []Check `json:"checks"`
+}
+
+// Add a message and lower the grade of the check
+func (c *Check) add(grade Grade, format string, args ...any) {
+	c.Messages = append(c.Messages, fmt.Sprintf(format, args...))
+	c.Grade = worst(c.Grade, grade)
+}
+
+func worst(a Grade, b Grade) Grade {
+	rank := map[Grade]int{GradePass: 0, GradeWarn: 1, GradeFail: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// CheckDomain checks the email authentication records of a domain. DKIM is only checked for
+// the given selectors. An error is returned when the resolver fails, missing records are
+// reported as failed checks.
+func CheckDomain(ctx context.Context, r Resolver, domain string, selectors []string) (Results, error) {
+	results := Results{Domain: domain, Grade: GradePass, MX: []string{}}
+
+	spf, lookups, err := checkSPF(ctx, r, domain)
+	if err != nil {
+		return Results{}, err
+	}
+	results.SPFLookups = lookups
+
+	dmarc, policy, err := checkDMARC(ctx, r, domain)
+	if err != nil {
+		return Results{}, err
+	}
+	results.DMARCPolicy = policy
+
+	mx, hosts, err := checkMX(ctx, r, domain)
+	if err != nil {
+		return Results{}, err
+	}
+	results.MX = hosts
+
+	results.Checks = []Check{spf, dmarc, mx}
+	for _, selector := range selectors {
+		dkim, err := checkDKIM(ctx, r, domain, selector)
+		if err != nil {
+			return Results{}, err
+		}
+		results.Checks = append(results.Checks, dkim)
+	}
+
+	for _, check := range results.Checks {
+		results.Grade = worst(results.Grade, check.Grade)
+	}
+	return results, nil
+}
+
+// Check the DMARC record at _dmarc of the domain, the policy has to quarantine or reject
+// failing mail for the check to pass
+func checkDMARC(ctx context.Context, r Resolver, domain string) (Check, string, error) {
+	check := Check{Name: CheckDMARC, Grade: GradePass, Messages: []string{}}
+
+	records, err := lookupRecords(ctx, r, "_dmarc."+domain, "v=DMARC1")
+	if err != nil {
+		return check, "", err
+	}
+	switch len(records) {
+	case 0:
+		check.add(GradeFail, "No DMARC record at _dmarc.%s", domain)
+		return check, "", nil
+	case 1:
+		check.Record = records[0]
+	default:
+		check.Record = records[0]
+		check.add(GradeFail, "%d DMARC records, receivers ignore all of them", len(records))
+		return check, "", nil
+	}
+
+	tags := parseTags(check.Record)
+	policy := strings.ToLower(tags["p"])
+	switch policy {
+	case "reject", "quarantine":
+	case "none":
+		check.add(GradeWarn, "The policy is none so mail failing DMARC is still delivered")
+	case "":
+		check.add(GradeFail, "The record has no p= policy")
+	default:
+		check.add(GradeFail, "Invalid policy %q", tags["p"])
+	}
+	if pct, ok := tags["pct"]; ok && pct != "100" {
+		check.add(GradeWarn, "The policy only applies to %s%% of the mail", pct)
+	}
+	return check, policy, nil
+}
+
+// Check the domain has mail servers that resolve, a null MX means it does not accept mail
+func checkMX(ctx context.Context, r Resolver, domain string) (Check, []string, error) {
+	check := Check{Name: CheckMX, Grade: GradePass, Messages: []string{}}
+	hosts := []string{}
+
+	records, err := r.LookupMX(ctx, domain+".")
+	if err != nil && !isNotFound(err) {
+		return check, hosts, lookupError(err)
+	}
+	for _, record := range records {
+		hosts = append(hosts, strings.TrimSuffix(record.Host, "."))
+	}
+	check.Record = strings.Join(hosts, ", ")
+
+	if len(hosts) == 0 {
+		check.add(GradeFail, "No MX records, replies and bounces can not be received")
+		return check, hosts, nil
+	}
+	if len(hosts) == 1 && (hosts[0] == "" || hosts[0] == ".") {
+		check.add(GradeFail, "Null MX record, the domain does not accept mail")
+		return check, hosts, nil
+	}
+
+	resolved := 0
+	for _, host := range hosts {
+		addresses, err := r.LookupHost(ctx, host+".")
+		if err != nil && !isNotFound(err) {
+			return check, hosts, lookupError(err)
+		}
+		if len(addresses) == 0 {
+			check.add(GradeWarn, "The mail server %s does not resolve", host)
+			continue
+		}
+		resolved++
+	}
+	if resolved == 0 {
+		check.add(GradeFail, "None of the mail servers resolve")
+	}
+	return check, hosts, nil
+}
+
+// Check a DKIM selector has a public key
+func checkDKIM(ctx context.Context, r Resolver, domain string, selector string) (Check, error) {
+	name := selector + "._domainkey." + domain
+	check := Check{Name: CheckDKIM + " " + selector, Grade: GradePass, Messages: []string{}}
+
+	txt, err := r.LookupTXT(ctx, name+".")
+	if err != nil && !isNotFound(err) {
+		return check, lookupError(err)
+	}
+	if len(txt) == 0 {
+		check.add(GradeFail, "No DKIM record at %s", name)
+		return check, nil
+	}
+
+	check.Record = txt[0]
+	tags := parseTags(check.Record)
+	if v, ok := tags["v"]; ok && !strings.EqualFold(v, "DKIM1") {
+		check.add(GradeFail, "Invalid version %q", v)
+	}
+	key, ok := tags["p"]
+	switch {
+	case !ok:
+		check.add(GradeFail, "The record has no p= public key")
+	case key == "":
+		check.add(GradeFail, "The key was revoked, p= is empty")
+	}
+	return check, nil
+}
+
+// TXT records of a name that start with the version tag, e.g. v=spf1
+func lookupRecords(ctx context.Context, r Resolver, name string, version string) ([]string, error) {
+	txt, err := r.LookupTXT(ctx, name+".")
+	if err != nil && !isNotFound(err) {
+		return nil, lookupError(err)
+	}
+
+	var records []string
+	for _, record := range txt {
+		record = strings.TrimSpace(record)
+		if strings.EqualFold(record, version) || strings.HasPrefix(strings.ToLower(record), strings.ToLower(version)+" ") ||
+			strings.HasPrefix(strings.ToLower(record), strings.ToLower(version)+";") {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Parse the tag=value list of a DMARC or DKIM record
+func parseTags(record string) map[string]string {
+	tags := map[string]string{}
+	for _, part := range strings.Split(record, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.ToLower(strings.TrimSpace(key))] = strings.Join(strings.Fields(value), "")
+	}
+	return tags
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// Lookup failures other than a missing record mean the resolver did not answer
+func lookupError(err error) error {
+	return fmt.Errorf("DNS lookup failed: %w", err)
+}
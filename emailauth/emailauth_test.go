@@ -0,0 +1,136 @@
+package emailauth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers/providertest"
+)
+
+// Start a fake DNS server and a resolver that sends every query to it
+func startResolver(t *testing.T) (*providertest.DNS, *net.Resolver) {
+	dns := providertest.NewDNS()
+	address := providertest.StartDNS(t, dns)
+
+	return dns, &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// Set up a domain that passes every check
+func goodDomain(dns *providertest.DNS, domain string) {
+	dns.SetTXT(domain, "v=spf1 mx include:_spf."+domain+" -all", "google-site-verification=abc")
+	dns.SetTXT("_spf."+domain, "v=spf1 ip4:192.0.2.0/24 ~all")
+	dns.SetTXT("_dmarc."+domain, "v=DMARC1; p=reject; rua=mailto:dmarc@"+domain)
+	dns.SetTXT("s1._domainkey."+domain, "v=DKIM1; k=rsa; p=MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQ")
+	dns.SetMX(domain, "mail."+domain)
+	dns.SetA("mail."+domain, "192.0.2.25")
+}
+
+// Messages of a check by name
+func messages(results Results, name string) string {
+	for _, check := range results.Checks {
+		if check.Name == name {
+			return strings.Join(check.Messages, "; ")
+		}
+	}
+	return ""
+}
+
+func TestCheckDomain(t *testing.T) {
+	ctx := context.Background()
+	dns, resolver := startResolver(t)
+
+	goodDomain(dns, "good.com")
+	results, err := CheckDomain(ctx, resolver, "good.com", []string{"s1"})
+	if err != nil {
+		t.Fatalf("CheckDomain: %v", err)
+	}
+	if results.Grade != GradePass {
+		t.Fatalf("expected a pass, got %s: %+v", results.Grade, results.Checks)
+	}
+	if results.SPFLookups != 2 || results.DMARCPolicy != "reject" || len(results.MX) != 1 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	goodDomain(dns, "monitor.com")
+	dns.SetTXT("_dmarc.monitor.com", "v=DMARC1; p=none")
+	results, err = CheckDomain(ctx, resolver, "monitor.com", nil)
+	if err != nil {
+		t.Fatalf("CheckDomain: %v", err)
+	}
+	if results.Grade != GradeWarn {
+		t.Errorf("expected a warning for p=none, got %s", results.Grade)
+	}
+
+	dns.SetA("bare.com", "192.0.2.1")
+	results, err = CheckDomain(ctx, resolver, "bare.com", []string{"s1"})
+	if err != nil {
+		t.Fatalf("CheckDomain: %v", err)
+	}
+	if results.Grade != GradeFail {
+		t.Errorf("expected a fail without records, got %s", results.Grade)
+	}
+	for _, name := range []string{CheckSPF, CheckDMARC, CheckMX, CheckDKIM + " s1"} {
+		if messages(results, name) == "" {
+			t.Errorf("expected %s to fail", name)
+		}
+	}
+}
+
+func TestSPF(t *testing.T) {
+	ctx := context.Background()
+	dns, resolver := startResolver(t)
+
+	// Each include needs a lookup, with the a and mx mechanisms the record needs 11
+	var includes []string
+	for i := range 9 {
+		name := fmt.Sprintf("_spf%d.long.com", i)
+		includes = append(includes, "include:"+name)
+		dns.SetTXT(name, "v=spf1 ip4:192.0.2.1 -all")
+	}
+	dns.SetTXT("long.com", "v=spf1 a mx "+strings.Join(includes, " ")+" -all")
+	dns.SetTXT("loop.com", "v=spf1 include:_spf.loop.com -all")
+	dns.SetTXT("_spf.loop.com", "v=spf1 include:loop.com -all")
+	dns.SetTXT("open.com", "v=spf1 +all")
+	dns.SetTXT("twice.com", "v=spf1 -all", "v=spf1 mx -all")
+	dns.SetTXT("missing.com", "v=spf1 include:nothing.com -all")
+	dns.SetTXT("redirect.com", "v=spf1 redirect=_spf.redirect.com")
+	dns.SetTXT("_spf.redirect.com", "v=spf1 mx -all")
+	dns.SetTXT("redirectopen.com", "v=spf1 redirect=_spf.redirectopen.com")
+	dns.SetTXT("_spf.redirectopen.com", "v=spf1 +all")
+	dns.SetTXT("redirectignored.com", "v=spf1 ip4:192.0.2.1 -all redirect=_spf.redirectopen.com")
+
+	tests := []struct {
+		domain  string
+		grade   Grade
+		lookups int
+		message string
+	}{
+		{"long.com", GradeFail, 11, "11 DNS lookups"},
+		{"loop.com", GradeFail, 2, "loops back"},
+		{"open.com", GradeFail, 0, "+all"},
+		{"twice.com", GradeFail, 0, "2 SPF records"},
+		{"missing.com", GradeFail, 1, "include:nothing.com"},
+		{"redirect.com", GradePass, 2, ""},
+		{"redirectopen.com", GradeFail, 1, "+all"},
+		{"redirectignored.com", GradePass, 0, ""},
+	}
+	for _, tt := range tests {
+		check, lookups, err := checkSPF(ctx, resolver, tt.domain)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.domain, err)
+		}
+		message := strings.Join(check.Messages, "; ")
+		if check.Grade != tt.grade || lookups != tt.lookups || !strings.Contains(message, tt.message) {
+			t.Errorf("%s: got %s with %d lookups (%s), want %s with %d lookups (%s)", tt.domain, check.Grade, lookups, message, tt.grade, tt.lookups, tt.message)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package emailauth
+
+import (
+	"context"
+	"strings"
+)
+
+// DNS lookups allowed to evaluate an SPF record (RFC 7208 section 4.6.4), receivers return a
+// permerror when a record needs more
+const MaxSPFLookups = 10
+
+// Walk of an SPF record and the records it includes
+type spfWalk struct {
+	ctx     context.Context
+	r       Resolver
+	check   *Check
+	lookups int
+
+	// Domains of the records being walked, to find include loops
+	visited map[string]bool
+}
+
+// Check the SPF record of the domain and count the DNS lookups it needs, following the includes
+// and redirects
+func checkSPF(ctx context.Context, r Resolver, domain string) (Check, int, error) {
+	check := Check{Name: CheckSPF, Grade: GradePass, Messages: []string{}}
+
+	records, err := lookupRecords(ctx, r, domain, "v=spf1")
+	if err != nil {
+		return check, 0, err
+	}
+	switch len(records) {
+	case 0:
+		check.add(GradeFail, "No SPF record")
+		return check, 0, nil
+	case 1:
+		check.Record = records[0]
+	default:
+		check.Record = records[0]
+		check.add(GradeFail, "%d SPF records, receivers return a permerror", len(records))
+		return check, 0, nil
+	}
+
+	walk := &spfWalk{ctx: ctx, r: r, check: &check, visited: map[string]bool{strings.ToLower(domain): true}}
+	all, known, err := walk.record(check.Record)
+	if err != nil {
+		return check, 0, err
+	}
+
+	switch {
+	case all == "+":
+		check.add(GradeFail, "+all lets any server send mail for the domain")
+	case all == "?":
+		check.add(GradeWarn, "?all treats mail from other servers as neutral")
+	case all == "" && known:
+		check.add(GradeWarn, "No all mechanism, mail from other servers is treated as neutral")
+	}
+	if walk.lookups > MaxSPFLookups {
+		check.add(GradeFail, "%d DNS lookups, at most %d are allowed and receivers return a permerror", walk.lookups, MaxSPFLookups)
+	}
+	return check, walk.lookups, nil
+}
+
+// Count the lookups of a record. The qualifier of the all mechanism that applies to mail from
+// other servers is returned, the one of the record or of the record it redirects to, and false
+// when it is not known because the redirect target can not be checked.
+func (w *spfWalk) record(record string) (string, bool, error) {
+	all := ""
+	redirect := ""
+
+	for _, term := range strings.Fields(record)[1:] {
+		qualifier := "+"
+		if strings.ContainsAny(term[:1], "+-~?") {
+			qualifier, term = term[:1], term[1:]
+		}
+
+		name, value := term, ""
+		if i := strings.IndexAny(term, ":=/"); i >= 0 {
+			name, value = term[:i], strings.TrimLeft(term[i:], ":=")
+		}
+
+		switch strings.ToLower(name) {
+		case "all":
+			all = qualifier
+		case "ip4", "ip6", "exp":
+		case "a", "mx", "exists":
+			w.lookups++
+		case "ptr":
+			w.lookups++
+			w.check.add(GradeWarn, "The ptr mechanism is deprecated and ignored by some receivers")
+		case "include":
+			w.lookups++
+			if _, _, err := w.include(value, "include:"+value); err != nil {
+				return "", false, err
+			}
+		case "redirect":
+			redirect = value
+		default:
+			// Unknown modifiers are ignored, unknown mechanisms are a permerror
+			if !strings.Contains(term, "=") {
+				w.check.add(GradeFail, "Unknown mechanism %q", term)
+			}
+		}
+	}
+
+	// A redirect is ignored when the record has an all mechanism (RFC 7208 section 6.1)
+	if all != "" || redirect == "" {
+		return all, true, nil
+	}
+	w.lookups++
+	return w.include(redirect, "redirect="+redirect)
+}
+
+// Follow an include or redirect, a target without a single SPF record is a permerror. The
+// qualifier of the all mechanism of the target is returned as it is by record.
+func (w *spfWalk) include(domain string, term string) (string, bool, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain == "" || strings.Contains(domain, "%") {
+		// Macros are expanded per message so the target can not be checked
+		return "", false, nil
+	}
+	if w.visited[domain] {
+		w.check.add(GradeFail, "%s loops back to a record that includes it", term)
+		return "", false, nil
+	}
+	w.visited[domain] = true
+	defer delete(w.visited, domain)
+
+	records, err := lookupRecords(w.ctx, w.r, domain, "v=spf1")
+	if err != nil {
+		return "", false, err
+	}
+	if len(records) != 1 {
+		w.check.add(GradeFail, "%s does not have a single SPF record", term)
+		return "", false, nil
+	}
+
+	return w.record(records[0])
+}
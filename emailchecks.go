@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/lum8rjack/redcompass/emailauth"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Cron job that checks the email authentication records of the domains every day
+const (
+	emailAuthJobID = "email-auth-checks"
+	emailAuthCron  = "0 7 * * *"
+)
+
+// Add the cron job that checks the email authentication records of the domains
+func AddEmailAuthCronJob() {
+	app.Cron().MustAdd(emailAuthJobID, emailAuthCron, func() {
+		CheckEmailAuth(jobsCtx)
+	})
+}
+
+// Check the SPF, DMARC, MX and DKIM records of the domains that are not expired or removed
+// from the provider and save the results to Email_Auth
+func CheckEmailAuth(ctx context.Context) {
+	msg := "EMAIL:email authentication checks"
+	app.Logger().Info(msg, "status", "started")
+
+	domains, err := app.FindAllRecords("Domains",
+		dbx.HashExp{"Is_Expired": false, "Removed_From_Provider": false},
+	)
+	if err != nil {
+		app.Logger().Error(msg, "function", "app.FindAllRecords", "error", err.Error())
+		return
+	}
+
+	failed := 0
+	for _, domain := range domains {
+		// Stop if the app is shutting down
+		if ctx.Err() != nil {
+			app.Logger().Warn(msg, "status", "cancelled", "error", ctx.Err().Error())
+			return
+		}
+
+		results, err := CheckDomainEmailAuth(ctx, domain)
+		if err != nil {
+			app.Logger().Error(msg, "function", "CheckDomainEmailAuth", "domain", domain.GetString("Name"), "error", err.Error())
+			continue
+		}
+		if results.Grade == emailauth.GradeFail {
+			failed++
+		}
+	}
+	app.Logger().Info(msg, "status", "completed", "domains", len(domains), "failed", failed)
+}
+
+// Check the email authentication records of a domain with the DKIM selectors saved on the
+// domain and save the results to Email_Auth
+func CheckDomainEmailAuth(ctx context.Context, domain *core.Record) (emailauth.Results, error) {
+	var selectors []string
+	if err := domain.UnmarshalJSONField("DKIM_Selectors", &selectors); err != nil {
+		return emailauth.Results{}, err
+	}
+
+	results, err := emailauth.CheckDomain(ctx, net.DefaultResolver, domain.GetString("Name"), selectors)
+	if err != nil {
+		return emailauth.Results{}, err
+	}
+
+	record, err := app.FindFirstRecordByData("Email_Auth", "Domain", domain.Id)
+	if err != nil {
+		collection, err := app.FindCollectionByNameOrId("Email_Auth")
+		if err != nil {
+			return emailauth.Results{}, err
+		}
+		record = core.NewRecord(collection)
+		record.Set("Domain", domain.Id)
+	}
+
+	record.Set("Grade", results.Grade)
+	record.Set("SPF_Lookups", results.SPFLookups)
+	record.Set("DMARC_Policy", results.DMARCPolicy)
+	record.Set("MX", results.MX)
+	record.Set("Checks", results.Checks)
+	record.Set("Last_Checked", time.Now().UTC())
+	for _, check := range results.Checks {
+		switch check.Name {
+		case emailauth.CheckSPF:
+			record.Set("SPF_Record", check.Record)
+		case emailauth.CheckDMARC:
+			record.Set("DMARC_Record", check.Record)
+		}
+	}
+
+	return results, app.Save(record)
+}
@@ -0,0 +1,101 @@
+// Add a registrar and a scanner written in JavaScript
+// They show up on the Settings page and run on the same cron jobs as the built-in providers
+
+registerProvider({
+    name: "Example Registrar",
+    kind: "registrar",
+
+    // JSON schema of the settings shown on the Settings page
+    settings: {
+        type: "object",
+        properties: {
+            apiKey: { type: "string", title: "API Key", format: "password", minLength: 10 },
+            baseUrl: { type: "string", title: "Base URL", format: "uri", description: "Optional, defaults to https://api.registrar.example" },
+        },
+        required: ["apiKey"],
+    },
+
+    // Return the domains in the account
+    getDomains: (settings) => {
+        const res = $http.send({
+            url: (settings.baseUrl || "https://api.registrar.example") + "/v1/domains",
+            headers: { "authorization": "Bearer " + settings.apiKey },
+            timeout: 60,
+        });
+
+        // Errors thrown with a status are handled like the HTTP status, e.g. a 401 disables the service
+        if (res.statusCode !== 200) {
+            throw { status: res.statusCode, retryAfter: res.headers["Retry-After"]?.[0], message: res.raw };
+        }
+
+        return res.json.domains.map((d) => ({
+            name: d.domain,
+            created: d.created_at,
+            expires: d.expires_at,
+            autoRenew: d.auto_renew,
+            isLocked: d.transfer_lock,
+            whoisGuard: d.privacy,
+            ourDNS: d.uses_registrar_dns,
+        }));
+    },
+
+    // Return the DNS records of a domain
+    getDomainRecords: (settings, domain) => {
+        const res = $http.send({
+            url: (settings.baseUrl || "https://api.registrar.example") + "/v1/domains/" + domain + "/records",
+            headers: { "authorization": "Bearer " + settings.apiKey },
+            timeout: 60,
+        });
+
+        if (res.statusCode !== 200) {
+            throw { status: res.statusCode, message: res.raw };
+        }
+
+        return res.json.records.map((r) => ({
+            id: r.id,
+            name: r.name,
+            type: r.type,
+            address: r.content,
+            ttl: r.ttl,
+            priority: r.priority,
+        }));
+    },
+
+    // createRecord, updateRecord and deleteRecord can be added to manage the records from RedCompass
+})
+
+registerProvider({
+    name: "In-house Reputation",
+    kind: "scanner",
+
+    settings: {
+        type: "object",
+        properties: {
+            apiKey: { type: "string", title: "API Key", format: "password" },
+        },
+        required: ["apiKey"],
+    },
+
+    // Return the analysis stats of a domain, they are saved like the VirusTotal results
+    getResults: (settings, domain) => {
+        const res = $http.send({
+            url: "https://reputation.internal.example/api/domains/" + domain,
+            headers: { "x-api-key": settings.apiKey },
+            timeout: 30,
+        });
+
+        if (res.statusCode !== 200) {
+            throw { status: res.statusCode, message: res.raw };
+        }
+
+        return {
+            malicious: res.json.malicious,
+            suspicious: res.json.suspicious,
+            harmless: res.json.clean,
+            undetected: res.json.unknown,
+        };
+    },
+
+    // Optional, without it the scanner has no daily limit
+    getDailyQuotaRemaining: (settings) => 1000,
+})
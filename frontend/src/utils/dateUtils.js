@@ -27,4 +27,24 @@ export const toUserDate = (date) => {
     console.error('Error converting to user date:', error)
     return ''
   }
-}
\ No newline at end of file
+}
+export const timeAgo = (date) => {
+  const then = new Date(date)
+  if (Number.isNaN(then.getTime())) {
+    return ''
+  }
+
+  const seconds = Math.max(0, Math.floor((Date.now() - then.getTime()) / 1000))
+  if (seconds < 60) {
+    return 'just now'
+  }
+  const minutes = Math.floor(seconds / 60)
+  if (minutes < 60) {
+    return `${minutes}m ago`
+  }
+  const hours = Math.floor(minutes / 60)
+  if (hours < 24) {
+    return `${hours}h ago`
+  }
+  return `${Math.floor(hours / 24)}d ago`
+}
@@ -0,0 +1,127 @@
+// Package health decides if a domain is healthy from its reputation results.
+package health
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Engine categories that count as flagging a domain
+const (
+	CategoryMalicious  = "malicious"
+	CategorySuspicious = "suspicious"
+)
+
+// Policy a domain has to pass to stay healthy. A threshold of 0 disables the check.
+type Policy struct {
+	// Unhealthy when the number of malicious detections is at least the threshold
+	MaliciousThreshold int `json:"maliciousThreshold"`
+
+	// Unhealthy when the number of suspicious detections is at least the threshold
+	SuspiciousThreshold int `json:"suspiciousThreshold"`
+
+	// Unhealthy when any of these engines flags the domain as malicious or suspicious
+	Engines []string `json:"engines"`
+
+	// Unhealthy when the domain or its addresses are listed on at least this many DNS blocklists
+	BlocklistThreshold int `json:"blocklistThreshold"`
+}
+
+// Policy used when none is configured: any malicious detection, at least two suspicious ones
+// or any blocklist listing
+var DefaultPolicy = Policy{MaliciousThreshold: 1, SuspiciousThreshold: 2, BlocklistThreshold: 1}
+
+// Reputation results of a domain
+type Results struct {
+	Malicious  int
+	Suspicious int
+
+	// Category reported by each engine, keyed by the engine name
+	Engines map[string]string
+
+	// Reasons of the listings on each DNS blocklist the domain is listed on, keyed by the zone
+	Blocklists map[string][]string
+}
+
+// Merge the results of another scanner. The highest detection counts are kept and an engine
+// keeps the category that flags the domain when the scanners disagree.
+func (r *Results) Merge(other Results) {
+	r.Malicious = max(r.Malicious, other.Malicious)
+	r.Suspicious = max(r.Suspicious, other.Suspicious)
+
+	if r.Engines == nil && len(other.Engines) > 0 {
+		r.Engines = map[string]string{}
+	}
+	for engine, category := range other.Engines {
+		if current, ok := r.Engines[engine]; !ok || categoryRank(category) > categoryRank(current) {
+			r.Engines[engine] = category
+		}
+	}
+
+	if r.Blocklists == nil && len(other.Blocklists) > 0 {
+		r.Blocklists = map[string][]string{}
+	}
+	maps.Copy(r.Blocklists, other.Blocklists)
+}
+
+// Evaluate the results against the policy. The reasons the domain is unhealthy are returned,
+// a domain without reasons is healthy.
+func (p Policy) Evaluate(r Results) []string {
+	var reasons []string
+
+	if p.MaliciousThreshold > 0 && r.Malicious >= p.MaliciousThreshold {
+		reasons = append(reasons, fmt.Sprintf("%s (threshold %d)", detections(r.Malicious, CategoryMalicious), p.MaliciousThreshold))
+	}
+	if p.SuspiciousThreshold > 0 && r.Suspicious >= p.SuspiciousThreshold {
+		reasons = append(reasons, fmt.Sprintf("%s (threshold %d)", detections(r.Suspicious, CategorySuspicious), p.SuspiciousThreshold))
+	}
+
+	// Engine names are matched case insensitive, VirusTotal uses names like "Fortinet" and "alphaMountain.ai"
+	engines := make([]string, 0, len(r.Engines))
+	for engine := range r.Engines {
+		engines = append(engines, engine)
+	}
+	slices.Sort(engines)
+
+	for _, engine := range engines {
+		category := r.Engines[engine]
+		if category != CategoryMalicious && category != CategorySuspicious {
+			continue
+		}
+		if slices.ContainsFunc(p.Engines, func(e string) bool { return strings.EqualFold(strings.TrimSpace(e), engine) }) {
+			reasons = append(reasons, "Flagged as "+category+" by "+engine)
+		}
+	}
+
+	if p.BlocklistThreshold > 0 && len(r.Blocklists) >= p.BlocklistThreshold {
+		for _, list := range slices.Sorted(maps.Keys(r.Blocklists)) {
+			reason := "Listed on " + list
+			if len(r.Blocklists[list]) > 0 {
+				reason += " (" + strings.Join(r.Blocklists[list], ", ") + ")"
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+
+	return reasons
+}
+
+// Rank of an engine category, a malicious verdict outranks a suspicious one
+func categoryRank(category string) int {
+	switch category {
+	case CategoryMalicious:
+		return 2
+	case CategorySuspicious:
+		return 1
+	}
+	return 0
+}
+
+func detections(n int, category string) string {
+	if n == 1 {
+		return "1 " + category + " detection"
+	}
+	return fmt.Sprintf("%d %s detections", n, category)
+}
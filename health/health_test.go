@@ -0,0 +1,100 @@
+package health
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		results Results
+		want    []string
+	}{
+		{
+			name:    "clean",
+			policy:  DefaultPolicy,
+			results: Results{Engines: map[string]string{"Fortinet": "harmless"}},
+		},
+		{
+			name:    "malicious",
+			policy:  DefaultPolicy,
+			results: Results{Malicious: 1},
+			want:    []string{"1 malicious detection (threshold 1)"},
+		},
+		{
+			name:    "suspicious below threshold",
+			policy:  DefaultPolicy,
+			results: Results{Suspicious: 1},
+		},
+		{
+			name:    "suspicious",
+			policy:  DefaultPolicy,
+			results: Results{Suspicious: 3},
+			want:    []string{"3 suspicious detections (threshold 2)"},
+		},
+		{
+			name:    "disabled thresholds",
+			policy:  Policy{},
+			results: Results{Malicious: 5, Suspicious: 5},
+		},
+		{
+			name:   "engines",
+			policy: Policy{Engines: []string{"fortinet", " Sophos "}},
+			results: Results{Suspicious: 1, Engines: map[string]string{
+				"Sophos":   "suspicious",
+				"Fortinet": "malicious",
+				"Webroot":  "malicious",
+			}},
+			want: []string{"Flagged as malicious by Fortinet", "Flagged as suspicious by Sophos"},
+		},
+		{
+			name:   "blocklists",
+			policy: DefaultPolicy,
+			results: Results{Blocklists: map[string][]string{
+				"zen.spamhaus.org": {"192.0.2.25: SBL spam source"},
+				"dbl.spamhaus.org": {"phishing domain"},
+			}},
+			want: []string{"Listed on dbl.spamhaus.org (phishing domain)", "Listed on zen.spamhaus.org (192.0.2.25: SBL spam source)"},
+		},
+		{
+			name:    "blocklists below threshold",
+			policy:  Policy{BlocklistThreshold: 2},
+			results: Results{Blocklists: map[string][]string{"multi.uribl.com": {"grey"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Evaluate(tt.results); !slices.Equal(got, tt.want) {
+				t.Errorf("Evaluate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	results := Results{
+		Malicious:  1,
+		Suspicious: 3,
+		Engines:    map[string]string{"Fortinet": "malicious", "Sophos": "harmless"},
+	}
+	results.Merge(Results{
+		Malicious:  2,
+		Suspicious: 0,
+		Engines:    map[string]string{"Fortinet": "harmless", "Sophos": "suspicious", "Webroot": "undetected"},
+		Blocklists: map[string][]string{"dbl.spamhaus.org": {"phishing domain"}},
+	})
+
+	want := Results{
+		Malicious:  2,
+		Suspicious: 3,
+		Engines:    map[string]string{"Fortinet": "malicious", "Sophos": "suspicious", "Webroot": "undetected"},
+		Blocklists: map[string][]string{"dbl.spamhaus.org": {"phishing domain"}},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Merge() = %+v, want %+v", results, want)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/health"
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Load the health policy from the Health_Policy collection. The default policy is used when
+// no policy is saved, false is returned when the saved policy is disabled.
+func loadHealthPolicy() (health.Policy, bool, error) {
+	records, err := app.FindRecordsByFilter("Health_Policy", "", "created", 1, 0)
+	if err != nil {
+		return health.Policy{}, false, err
+	}
+	if len(records) == 0 {
+		return health.DefaultPolicy, true, nil
+	}
+
+	record := records[0]
+	policy := health.Policy{
+		MaliciousThreshold:  record.GetInt("Malicious_Threshold"),
+		SuspiciousThreshold: record.GetInt("Suspicious_Threshold"),
+		BlocklistThreshold:  record.GetInt("Blocklist_Threshold"),
+	}
+	if err := record.UnmarshalJSONField("Engines", &policy.Engines); err != nil {
+		return health.Policy{}, false, err
+	}
+	return policy, record.GetBool("Enabled"), nil
+}
+
+// Latest reputation results of a domain from every scanner merged together
+func domainHealthResults(domain *core.Record) (health.Results, error) {
+	results := health.Results{Engines: map[string]string{}, Blocklists: map[string][]string{}}
+	for _, name := range providers.Names(providers.KindScanner) {
+		scan, err := scannerStore(name).HealthResults(app, domain)
+		if err != nil {
+			return health.Results{}, err
+		}
+		results.Merge(scan)
+	}
+	return results, nil
+}
+
+// Evaluate the health policy after a scan and update the Healthy flag of the domain. The latest
+// results of every scanner are evaluated so a clean scan does not hide a listing of another one.
+// A domain that fails the policy is marked unhealthy with the reasons, and is marked healthy
+// again once it passes. Domains marked unhealthy by hand have no reason and are left unhealthy.
+func EvaluateDomainHealth(domainName string) error {
+	policy, enabled, err := loadHealthPolicy()
+	if err != nil || !enabled {
+		return err
+	}
+
+	domain, err := app.FindFirstRecordByData("Domains", "Name", domainName)
+	if err != nil {
+		return err
+	}
+
+	results, err := domainHealthResults(domain)
+	if err != nil {
+		return err
+	}
+
+	reasons := policy.Evaluate(results)
+	reason := strings.Join(reasons, ", ")
+	wasHealthy := domain.GetBool("Healthy")
+
+	switch {
+	case len(reasons) > 0 && wasHealthy:
+		domain.Set("Healthy", false)
+		domain.Set("Unhealthy_Reason", reason)
+		domain.Set("Unhealthy_Date", time.Now().UTC())
+	case len(reasons) > 0 && domain.GetString("Unhealthy_Reason") != "" && domain.GetString("Unhealthy_Reason") != reason:
+		domain.Set("Unhealthy_Reason", reason)
+	case len(reasons) == 0 && !wasHealthy && domain.GetString("Unhealthy_Reason") != "":
+		domain.Set("Healthy", true)
+		domain.Set("Unhealthy_Reason", "")
+		domain.Set("Unhealthy_Date", "")
+	default:
+		return nil
+	}
+
+	if err := app.Save(domain); err != nil {
+		return err
+	}
+
+	if wasHealthy && !domain.GetBool("Healthy") {
+		app.Logger().Info("HEALTH:"+domainName, "status", "unhealthy", "reason", reason)
+		if project := activeProject(domain); project != nil {
+			notifyDomainBurned(domain, project)
+		}
+	}
+	return nil
+}
+
+// Project the domain is assigned to if it is not completed yet
+func activeProject(domain *core.Record) *core.Record {
+	projectID := domain.GetString("Assigned_Project")
+	if projectID == "" {
+		return nil
+	}
+
+	project, err := app.FindRecordById("Projects", projectID)
+	if err != nil || project.GetBool("Completed") {
+		return nil
+	}
+	return project
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"slices"
+	"time"
+
+	"github.com/lum8rjack/redcompass/providers"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 )
 
 func SetupHooks() {
 	bootstrapHook()
+	serveHook()
+	settingsHook()
 	createHook()
 	updateHook()
 	deleteHook()
+	terminateHook()
 }
 
 func bootstrapHook() {
@@ -18,11 +25,122 @@ func bootstrapHook() {
 			return err
 		}
 
+		checkEncryptionKey()
 		checkAllServices()
+		AddExpiringDomainsCronJob()
+		AddEmailAuthCronJob()
 		return nil
 	})
 }
 
+func serveHook() {
+	// The migrations have been applied when the server starts, the registered providers can be saved
+	app.OnServe().BindFunc(func(e *core.ServeEvent) error {
+		syncProviderField()
+		return e.Next()
+	})
+}
+
+// Set the values of the Services Provider field to the registered providers, so adding a provider
+// does not need a migration
+func syncProviderField() {
+	msg := "SETTINGS: serve hook"
+
+	collection, err := app.FindCollectionByNameOrId("Services")
+	if err != nil {
+		app.Logger().Error(msg, "function", "FindCollectionByNameOrId", "error", err.Error())
+		return
+	}
+
+	field, ok := collection.Fields.GetByName("Provider").(*core.SelectField)
+	if !ok {
+		app.Logger().Error(msg, "function", "Fields.GetByName", "error", "the Provider field is not a select field")
+		return
+	}
+
+	// Providers that are no longer registered are kept so their services can still be loaded
+	var added []string
+	for _, name := range providers.Names("") {
+		if !slices.Contains(field.Values, name) {
+			added = append(added, name)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	field.Values = append(field.Values, added...)
+
+	err = app.Save(collection)
+	if err != nil {
+		app.Logger().Error(msg, "function", "Save", "error", err.Error())
+		return
+	}
+	app.Logger().Info(msg, "status", "updated", "added", added)
+}
+
+// On startup, check the encryption key and encrypt any service settings that are not encrypted yet
+func checkEncryptionKey() {
+	msg := "SETTINGS: startup hook"
+
+	key, err := getEncryptionKey()
+	if err != nil {
+		app.Logger().Error(msg, "function", "getEncryptionKey", "error", err.Error())
+		return
+	}
+
+	if key == "" {
+		app.Logger().Warn(msg, "status", "unencrypted", "note", "set "+encryptionKeyEnv+" or "+encryptionKeyFileEnv+" to encrypt the service settings")
+		return
+	}
+
+	err = encryptAllServiceSettings()
+	if err != nil {
+		app.Logger().Error(msg, "function", "encryptAllServiceSettings", "error", err.Error())
+	}
+}
+
+func settingsHook() {
+	// Encrypt the service and notification channel settings before they are saved
+	app.OnRecordCreate(encryptedCollections...).BindFunc(func(e *core.RecordEvent) error {
+		if err := encryptServiceSettings(e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+
+	app.OnRecordUpdate(encryptedCollections...).BindFunc(func(e *core.RecordEvent) error {
+		if err := encryptServiceSettings(e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+
+	// Saving a service from the Settings page enables it again after it was disabled
+	app.OnRecordUpdateRequest("Services").BindFunc(func(e *core.RecordRequestEvent) error {
+		e.Record.Set("Disabled", false)
+		e.Record.Set("Disabled_Reason", "")
+		return e.Next()
+	})
+
+	// Mask the secrets whenever a service or notification channel is returned by the API
+	app.OnRecordEnrich(encryptedCollections...).BindFunc(func(e *core.RecordEnrichEvent) error {
+		settings, err := GetServiceSettings(e.Record)
+		if err != nil {
+			e.App.Logger().Error("SETTINGS:"+serviceLogName(e.Record)+" enrich hook", "function", "GetServiceSettings", "error", err.Error())
+			e.Record.Set("Settings", map[string]any{})
+			return e.Next()
+		}
+
+		masked, err := MaskSettings(settings)
+		if err != nil {
+			return err
+		}
+		e.Record.Set("Settings", masked)
+
+		return e.Next()
+	})
+}
+
 // On startup, loop through all services and add the cron job
 func checkAllServices() {
 	services, err := app.FindAllRecords("Services")
@@ -32,84 +150,81 @@ func checkAllServices() {
 	}
 
 	for _, service := range services {
-		serviceName := service.GetString("Provider")
-		if serviceName == "" {
+		if service.GetString("Provider") == "" {
 			continue
 		}
 
-		msg := "CRON:" + serviceName + " startup hook"
-		if serviceName == "VirusTotal" {
-			err := AddVirusTotalCronJob(service)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddVirusTotalCronJob", "error", err.Error())
-				continue
-			}
-			app.Logger().Info(msg, "status", "created", "jobID", serviceName)
-			continue
-		} else {
-			err := AddDomainsCronJob(service)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddDomainsCronJob", "error", err.Error())
-				continue
-			}
-			app.Logger().Info(msg, "status", "created", "jobID", serviceName)
-			continue
+		addServiceCronJob(service, "CRON:"+serviceLogName(service)+" startup hook")
+	}
+
+}
+
+// Add the cron job for a service unless it was disabled after an authentication failure
+func addServiceCronJob(service *core.Record, msg string) {
+	if service.GetBool("Disabled") {
+		app.Logger().Warn(msg, "status", "disabled", "reason", service.GetString("Disabled_Reason"), "note", "save the service settings to enable it again")
+		return
+	}
+
+	provider, ok := providers.Lookup(service.GetString("Provider"))
+	if !ok {
+		app.Logger().Error(msg, "function", "providers.Lookup", "error", "provider "+service.GetString("Provider")+" is not registered")
+		return
+	}
+
+	switch provider.Kind {
+	case providers.KindScanner:
+		err := AddScannerCronJob(service)
+		if err != nil {
+			app.Logger().Error(msg, "function", "AddScannerCronJob", "error", err.Error())
+			return
+		}
+	case providers.KindRegistrar:
+		err := AddDomainsCronJob(service)
+		if err != nil {
+			app.Logger().Error(msg, "function", "AddDomainsCronJob", "error", err.Error())
+			return
+		}
+	case providers.KindChecker:
+		err := AddCheckerCronJob(service)
+		if err != nil {
+			app.Logger().Error(msg, "function", "AddCheckerCronJob", "error", err.Error())
+			return
 		}
 	}
 
+	app.Logger().Info(msg, "status", "created", "jobID", service.Id)
 }
 
 func createHook() {
-	app.OnRecordCreate("Services").BindFunc(func(e *core.RecordEvent) error {
-		msg := "CRON:" + e.Record.GetString("Provider") + " create hook"
-		if e.Record.GetString("Provider") == "VirusTotal" {
-			err := AddVirusTotalCronJob(e.Record)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddVirusTotalCronJob", "error", err.Error())
-				return e.Next()
-			}
-			app.Logger().Info(msg, "status", "created", "jobID", e.Record.GetString("Provider"))
-			return e.Next()
-		} else {
-			err := AddDomainsCronJob(e.Record)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddDomainsCronJob", "error", err.Error())
-				return e.Next()
-			}
-			app.Logger().Info(msg, "status", "created", "jobID", e.Record.GetString("Provider"))
-			return e.Next()
-		}
+	// The cron job is keyed by the record id so it is added once the record is saved
+	app.OnRecordAfterCreateSuccess("Services").BindFunc(func(e *core.RecordEvent) error {
+		addServiceCronJob(e.Record, "CRON:"+serviceLogName(e.Record)+" create hook")
+		return e.Next()
 	})
 }
 
 func updateHook() {
 	// When a service is updated, remove the cron job and add a new and updated cron job
 	app.OnRecordAfterUpdateSuccess("Services").BindFunc(func(e *core.RecordEvent) error {
-		msg := "CRON:" + e.Record.GetString("Provider") + " update hook"
+		msg := "CRON:" + serviceLogName(e.Record) + " update hook"
 
-		err := RemoveCronJob(e.Record.GetString("Provider"))
+		err := RemoveCronJob(e.Record.Id)
 		if err != nil {
 			app.Logger().Error(msg, "function", "RemoveCronJob", "error", err.Error())
 			return e.Next()
 		}
 
-		if e.Record.GetString("Provider") == "VirusTotal" {
-			err := AddVirusTotalCronJob(e.Record)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddVirusTotalCronJob", "error", err.Error())
-				return e.Next()
-			}
-			app.Logger().Info(msg, "status", "created", "jobID", e.Record.GetString("Provider"))
-			return e.Next()
-		} else {
-			err := AddDomainsCronJob(e.Record)
-			if err != nil {
-				app.Logger().Error(msg, "function", "AddDomainsCronJob", "error", err.Error())
-				return e.Next()
-			}
-			app.Logger().Info(msg, "status", "created", "jobID", e.Record.GetString("Provider"))
-			return e.Next()
+		addServiceCronJob(e.Record, msg)
+		return e.Next()
+	})
+
+	// Notify when a domain is no longer healthy
+	app.OnRecordAfterUpdateSuccess("Domains").BindFunc(func(e *core.RecordEvent) error {
+		if e.Record.Original().GetBool("Healthy") && !e.Record.GetBool("Healthy") {
+			notifyDomainUnhealthy(e.Record)
 		}
+		return e.Next()
 	})
 
 	// When a project is completed, unassign all domains from the project and set the last used project to the project id
@@ -135,6 +250,10 @@ func updateHook() {
 			}
 			app.Logger().Info(msg, "projectID", e.Record.GetString("id"), "status", "completed", "note", "unassigned all domains from the project")
 
+			if !e.Record.Original().GetBool("Completed") {
+				notifyProjectCompleted(e.Record, len(assignedDomains))
+			}
+
 		}
 		return e.Next()
 	})
@@ -142,13 +261,29 @@ func updateHook() {
 
 func deleteHook() {
 	app.OnRecordDelete("Services").BindFunc(func(e *core.RecordEvent) error {
-		msg := "CRON:" + e.Record.GetString("Provider") + " delete hook"
-		err := RemoveCronJob(e.Record.GetString("Provider"))
+		msg := "CRON:" + serviceLogName(e.Record) + " delete hook"
+		err := RemoveCronJob(e.Record.Id)
 		if err != nil {
 			app.Logger().Error(msg, "function", "RemoveCronJob", "error", err.Error())
 			return e.Next()
 		}
-		app.Logger().Info(msg, "status", "deleted", "jobID", e.Record.GetString("Provider"))
+		app.Logger().Info(msg, "status", "deleted", "jobID", e.Record.Id)
+
+		return e.Next()
+	})
+}
+
+func terminateHook() {
+	// Stop scheduling new runs and cancel the running ones so the app does not wait on rate limiters
+	app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		msg := "CRON: terminate hook"
+		app.Cron().Stop()
+
+		if !stopJobs(15 * time.Second) {
+			app.Logger().Warn(msg, "status", "timeout", "note", "job runs did not finish before shutdown")
+		} else {
+			app.Logger().Info(msg, "status", "stopped")
+		}
 
 		return e.Next()
 	})
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Jobs that are recorded in Job_Runs
+const (
+	JobSync  = "sync"
+	JobScan  = "scan"
+	JobCheck = "check"
+)
+
+// What started a job run
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// Status of a job run
+const (
+	JobRunRunning = "running"
+	JobRunSuccess = "success"
+	JobRunPartial = "partial"
+	JobRunFailed  = "failed"
+)
+
+// Only the first errors are stored so a broken account does not create huge records
+const maxJobRunErrors = 100
+
+// Locks for each service so a manual run and a scheduled run never overlap
+var serviceLocks sync.Map
+
+// Context for the job runs, cancelled when the app is terminated so rate limiter waits and
+// provider requests stop right away
+var jobsCtx, cancelJobs = context.WithCancel(context.Background())
+
+// Job runs in progress, used to give them time to save their results on shutdown
+var runningJobs sync.WaitGroup
+
+// Lock a service for a job run. It returns false if another run for the service is in progress,
+// otherwise the returned function must be called once the run is finished.
+func lockService(serviceID string) (func(), bool) {
+	lock, _ := serviceLocks.LoadOrStore(serviceID, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
+	}
+
+	runningJobs.Add(1)
+	return func() {
+		mu.Unlock()
+		runningJobs.Done()
+	}, true
+}
+
+// Cancel the job runs in progress and wait for them to finish, up to the timeout
+func stopJobs(timeout time.Duration) bool {
+	cancelJobs()
+
+	done := make(chan struct{})
+	go func() {
+		runningJobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Error that happened during a job run
+type JobRunError struct {
+	Domain   string `json:"domain,omitempty"`
+	Function string `json:"function"`
+	Error    string `json:"error"`
+}
+
+// A single execution of a cron job, saved to the Job_Runs collection when it starts and finishes
+type JobRun struct {
+	record           *core.Record
+	service          *core.Record
+	job              string
+	DomainsProcessed int
+	DomainsSkipped   int
+	RecordsChanged   int
+	Errors           []JobRunError
+	errorCount       int
+	failed           bool
+}
+
+// Start a new job run for the service
+func StartJobRun(service *core.Record, job string, trigger string) *JobRun {
+	run := &JobRun{service: service, job: job}
+
+	collection, err := app.FindCollectionByNameOrId("Job_Runs")
+	if err != nil {
+		app.Logger().Error("JOBS:"+serviceLogName(service)+" start job run", "function", "app.FindCollectionByNameOrId", "error", err.Error())
+		return run
+	}
+
+	run.record = core.NewRecord(collection)
+	run.record.Set("Service", service.Id)
+	run.record.Set("Job", job)
+	run.record.Set("Trigger", trigger)
+	run.record.Set("Status", JobRunRunning)
+	run.record.Set("Started", time.Now().UTC())
+	run.save()
+
+	return run
+}
+
+// Add an error for a single domain, the job keeps running
+func (r *JobRun) AddError(domain string, function string, err error) {
+	r.errorCount++
+	if len(r.Errors) < maxJobRunErrors {
+		r.Errors = append(r.Errors, JobRunError{Domain: domain, Function: function, Error: err.Error()})
+	}
+}
+
+// Add an error that stopped the job
+func (r *JobRun) Fail(function string, err error) {
+	r.AddError("", function, err)
+	r.failed = true
+}
+
+// Get the final status of the run
+func (r *JobRun) Status() string {
+	switch {
+	case r.failed:
+		return JobRunFailed
+	case r.errorCount > 0:
+		return JobRunPartial
+	default:
+		return JobRunSuccess
+	}
+}
+
+// Finish the run and save the results, a failed sync is notified unless the app is shutting down
+func (r *JobRun) Finish() {
+	if r.failed && r.job == JobSync && jobsCtx.Err() == nil {
+		notifySyncFailed(r.service, r.Errors)
+	}
+
+	if r.record == nil {
+		return
+	}
+
+	r.record.Set("Status", r.Status())
+	r.record.Set("Finished", time.Now().UTC())
+	r.record.Set("Domains_Processed", r.DomainsProcessed)
+	r.record.Set("Domains_Skipped", r.DomainsSkipped)
+	r.record.Set("Records_Changed", r.RecordsChanged)
+	r.record.Set("Error_Count", r.errorCount)
+	r.record.Set("Errors", r.Errors)
+	r.save()
+}
+
+func (r *JobRun) save() {
+	if err := app.Save(r.record); err != nil {
+		app.Logger().Error("JOBS:"+r.record.GetString("Job")+" save job run", "function", "app.Save", "error", err.Error())
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	_ "github.com/lum8rjack/redcompass/migrations"
+	"github.com/lum8rjack/redcompass/providers/jsproviders"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
@@ -69,9 +70,32 @@ func main() {
 		},
 	})
 
-	// Setup JavaScript enginge to use with hooks
+	// Add a command to re-encrypt the service settings with a new encryption key
+	app.RootCmd.AddCommand(&cobra.Command{
+		Use:   "rotate-key [new key]",
+		Short: "Re-encrypt the service settings with a new 32 character encryption key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldKey, err := getEncryptionKey()
+			if err != nil {
+				return err
+			}
+
+			rotated, err := rotateEncryptionKey(oldKey, args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("re-encrypted the settings for %d services and notification channels\n", rotated)
+			fmt.Printf("update %s or %s with the new key before starting the server\n", encryptionKeyEnv, encryptionKeyFileEnv)
+			return nil
+		},
+	})
+
+	// Setup JavaScript enginge to use with hooks, the hooks can also register custom providers
 	jsvm.MustRegister(app, jsvm.Config{
 		HooksWatch: true,
+		OnInit:     jsproviders.Bind,
 	})
 
 	// Setup Migration command
@@ -97,6 +121,7 @@ func main() {
 
 	// Add additional routes
 	app.OnServe().BindFunc(RouteRoot)
+	app.OnServe().BindFunc(RouteAPI)
 
 	// When a user is created, set their role to "viewer" if its not already set
 	app.OnRecordAfterCreateSuccess("users").BindFunc(func(e *core.RecordEvent) error {
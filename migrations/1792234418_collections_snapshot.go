@@ -0,0 +1,2416 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `[
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text455797646",
+						"max": 0,
+						"min": 0,
+						"name": "collectionRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text127846527",
+						"max": 0,
+						"min": 0,
+						"name": "recordRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1582905952",
+						"max": 0,
+						"min": 0,
+						"name": "method",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_2279338944",
+				"indexes": [
+					"CREATE INDEX ` + "`" + `idx_mfas_collectionRef_recordRef` + "`" + ` ON ` + "`" + `_mfas` + "`" + ` (collectionRef,recordRef)"
+				],
+				"listRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId",
+				"name": "_mfas",
+				"system": true,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId"
+			},
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text455797646",
+						"max": 0,
+						"min": 0,
+						"name": "collectionRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text127846527",
+						"max": 0,
+						"min": 0,
+						"name": "recordRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cost": 8,
+						"hidden": true,
+						"id": "password901924565",
+						"max": 0,
+						"min": 0,
+						"name": "password",
+						"pattern": "",
+						"presentable": false,
+						"required": true,
+						"system": true,
+						"type": "password"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": true,
+						"id": "text3866985172",
+						"max": 0,
+						"min": 0,
+						"name": "sentTo",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_1638494021",
+				"indexes": [
+					"CREATE INDEX ` + "`" + `idx_otps_collectionRef_recordRef` + "`" + ` ON ` + "`" + `_otps` + "`" + ` (collectionRef, recordRef)"
+				],
+				"listRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId",
+				"name": "_otps",
+				"system": true,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId"
+			},
+			{
+				"createRule": null,
+				"deleteRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text455797646",
+						"max": 0,
+						"min": 0,
+						"name": "collectionRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text127846527",
+						"max": 0,
+						"min": 0,
+						"name": "recordRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text2462348188",
+						"max": 0,
+						"min": 0,
+						"name": "provider",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1044722854",
+						"max": 0,
+						"min": 0,
+						"name": "providerId",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_2281828961",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_externalAuths_record_provider` + "`" + ` ON ` + "`" + `_externalAuths` + "`" + ` (collectionRef, recordRef, provider)",
+					"CREATE UNIQUE INDEX ` + "`" + `idx_externalAuths_collection_provider` + "`" + ` ON ` + "`" + `_externalAuths` + "`" + ` (collectionRef, provider, providerId)"
+				],
+				"listRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId",
+				"name": "_externalAuths",
+				"system": true,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId"
+			},
+			{
+				"createRule": null,
+				"deleteRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text455797646",
+						"max": 0,
+						"min": 0,
+						"name": "collectionRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text127846527",
+						"max": 0,
+						"min": 0,
+						"name": "recordRef",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text4228609354",
+						"max": 0,
+						"min": 0,
+						"name": "fingerprint",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_4275539003",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_authOrigins_unique_pairs` + "`" + ` ON ` + "`" + `_authOrigins` + "`" + ` (collectionRef, recordRef, fingerprint)"
+				],
+				"listRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId",
+				"name": "_authOrigins",
+				"system": true,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != '' && recordRef = @request.auth.id && collectionRef = @request.auth.collectionId"
+			},
+			{
+				"authAlert": {
+					"emailTemplate": {
+						"body": "<p>Hello,</p>\n<p>We noticed a login to your {APP_NAME} account from a new location:</p>\n<p><em>{ALERT_INFO}</em></p>\n<p><strong>If this wasn't you, you should immediately change your {APP_NAME} account password to revoke access from all other locations.</strong></p>\n<p>If this was you, you may disregard this email.</p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+						"subject": "Login from a new location"
+					},
+					"enabled": true
+				},
+				"authRule": "",
+				"authToken": {
+					"duration": 86400
+				},
+				"confirmEmailChangeTemplate": {
+					"body": "<p>Hello,</p>\n<p>Click on the button below to confirm your new email address.</p>\n<p>\n  <a class=\"btn\" href=\"{APP_URL}/_/#/auth/confirm-email-change/{TOKEN}\" target=\"_blank\" rel=\"noopener\">Confirm new email</a>\n</p>\n<p><i>If you didn't ask to change your email address, you can ignore this email.</i></p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+					"subject": "Confirm your {APP_NAME} new email address"
+				},
+				"createRule": null,
+				"deleteRule": null,
+				"emailChangeToken": {
+					"duration": 1800
+				},
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cost": 0,
+						"hidden": true,
+						"id": "password901924565",
+						"max": 0,
+						"min": 8,
+						"name": "password",
+						"pattern": "",
+						"presentable": false,
+						"required": true,
+						"system": true,
+						"type": "password"
+					},
+					{
+						"autogeneratePattern": "[a-zA-Z0-9]{50}",
+						"hidden": true,
+						"id": "text2504183744",
+						"max": 60,
+						"min": 30,
+						"name": "tokenKey",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"exceptDomains": null,
+						"hidden": false,
+						"id": "email3885137012",
+						"name": "email",
+						"onlyDomains": null,
+						"presentable": false,
+						"required": true,
+						"system": true,
+						"type": "email"
+					},
+					{
+						"hidden": false,
+						"id": "bool1547992806",
+						"name": "emailVisibility",
+						"presentable": false,
+						"required": false,
+						"system": true,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "bool256245529",
+						"name": "verified",
+						"presentable": false,
+						"required": false,
+						"system": true,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": true,
+						"type": "autodate"
+					}
+				],
+				"fileToken": {
+					"duration": 180
+				},
+				"id": "pbc_3142635823",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_tokenKey_pbc_3142635823` + "`" + ` ON ` + "`" + `_superusers` + "`" + ` (` + "`" + `tokenKey` + "`" + `)",
+					"CREATE UNIQUE INDEX ` + "`" + `idx_email_pbc_3142635823` + "`" + ` ON ` + "`" + `_superusers` + "`" + ` (` + "`" + `email` + "`" + `) WHERE ` + "`" + `email` + "`" + ` != ''"
+				],
+				"listRule": null,
+				"manageRule": null,
+				"mfa": {
+					"duration": 1800,
+					"enabled": false,
+					"rule": ""
+				},
+				"name": "_superusers",
+				"oauth2": {
+					"enabled": false,
+					"mappedFields": {
+						"avatarURL": "",
+						"id": "",
+						"name": "",
+						"username": ""
+					}
+				},
+				"otp": {
+					"duration": 180,
+					"emailTemplate": {
+						"body": "<p>Hello,</p>\n<p>Your one-time password is: <strong>{OTP}</strong></p>\n<p><i>If you didn't ask for the one-time password, you can ignore this email.</i></p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+						"subject": "OTP for {APP_NAME}"
+					},
+					"enabled": false,
+					"length": 8
+				},
+				"passwordAuth": {
+					"enabled": true,
+					"identityFields": [
+						"email"
+					]
+				},
+				"passwordResetToken": {
+					"duration": 1800
+				},
+				"resetPasswordTemplate": {
+					"body": "<p>Hello,</p>\n<p>Click on the button below to reset your password.</p>\n<p>\n  <a class=\"btn\" href=\"{APP_URL}/_/#/auth/confirm-password-reset/{TOKEN}\" target=\"_blank\" rel=\"noopener\">Reset password</a>\n</p>\n<p><i>If you didn't ask to reset your password, you can ignore this email.</i></p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+					"subject": "Reset your {APP_NAME} password"
+				},
+				"system": true,
+				"type": "auth",
+				"updateRule": null,
+				"verificationTemplate": {
+					"body": "<p>Hello,</p>\n<p>Thank you for joining us at {APP_NAME}.</p>\n<p>Click on the button below to verify your email address.</p>\n<p>\n  <a class=\"btn\" href=\"{APP_URL}/_/#/auth/confirm-verification/{TOKEN}\" target=\"_blank\" rel=\"noopener\">Verify</a>\n</p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+					"subject": "Verify your {APP_NAME} email"
+				},
+				"verificationToken": {
+					"duration": 259200
+				},
+				"viewRule": null
+			},
+			{
+				"authAlert": {
+					"emailTemplate": {
+						"body": "<p>Hello,</p>\n<p>We noticed a login to your {APP_NAME} account from a new location:</p>\n<p><em>{ALERT_INFO}</em></p>\n<p><strong>If this wasn't you, you should immediately change your {APP_NAME} account password to revoke access from all other locations.</strong></p>\n<p>If this was you, you may disregard this email.</p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+						"subject": "Login from a new location"
+					},
+					"enabled": false
+				},
+				"authRule": "",
+				"authToken": {
+					"duration": 14400
+				},
+				"confirmEmailChangeTemplate": {
+					"body": "<p>Hello,</p>\n<p>Click on the button below to confirm your new email address.</p>\n<p>\n  <a class=\"btn\" href=\"{APP_URL}/_/#/auth/confirm-email-change/{TOKEN}\" target=\"_blank\" rel=\"noopener\">Confirm new email</a>\n</p>\n<p><i>If you didn't ask to change your email address, you can ignore this email.</i></p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+					"subject": "Confirm your {APP_NAME} new email address"
+				},
+				"createRule": "",
+				"deleteRule": "id = @request.auth.id",
+				"emailChangeToken": {
+					"duration": 1800
+				},
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cost": 0,
+						"hidden": true,
+						"id": "password901924565",
+						"max": 0,
+						"min": 8,
+						"name": "password",
+						"pattern": "",
+						"presentable": false,
+						"required": true,
+						"system": true,
+						"type": "password"
+					},
+					{
+						"autogeneratePattern": "[a-zA-Z0-9]{50}",
+						"hidden": true,
+						"id": "text2504183744",
+						"max": 60,
+						"min": 30,
+						"name": "tokenKey",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"exceptDomains": null,
+						"hidden": false,
+						"id": "email3885137012",
+						"name": "email",
+						"onlyDomains": null,
+						"presentable": false,
+						"required": true,
+						"system": true,
+						"type": "email"
+					},
+					{
+						"hidden": false,
+						"id": "bool1547992806",
+						"name": "emailVisibility",
+						"presentable": false,
+						"required": false,
+						"system": true,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "bool256245529",
+						"name": "verified",
+						"presentable": false,
+						"required": false,
+						"system": true,
+						"type": "bool"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1579384326",
+						"max": 255,
+						"min": 0,
+						"name": "name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "file376926767",
+						"maxSelect": 1,
+						"maxSize": 0,
+						"mimeTypes": [
+							"image/jpeg",
+							"image/png",
+							"image/svg+xml",
+							"image/gif",
+							"image/webp"
+						],
+						"name": "avatar",
+						"presentable": false,
+						"protected": false,
+						"required": false,
+						"system": false,
+						"thumbs": null,
+						"type": "file"
+					},
+					{
+						"hidden": false,
+						"id": "select1466534506",
+						"maxSelect": 1,
+						"name": "role",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "select",
+						"values": [
+							"viewer",
+							"user",
+							"admin"
+						]
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"fileToken": {
+					"duration": 180
+				},
+				"id": "_pb_users_auth_",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_tokenKey__pb_users_auth_` + "`" + ` ON ` + "`" + `users` + "`" + ` (` + "`" + `tokenKey` + "`" + `)",
+					"CREATE UNIQUE INDEX ` + "`" + `idx_email__pb_users_auth_` + "`" + ` ON ` + "`" + `users` + "`" + ` (` + "`" + `email` + "`" + `) WHERE ` + "`" + `email` + "`" + ` != ''"
+				],
+				"listRule": "",
+				"manageRule": null,
+				"mfa": {
+					"duration": 1800,
+					"enabled": false,
+					"rule": ""
+				},
+				"name": "users",
+				"oauth2": {
+					"enabled": false,
+					"mappedFields": {
+						"avatarURL": "avatar",
+						"id": "",
+						"name": "name",
+						"username": ""
+					}
+				},
+				"otp": {
+					"duration": 180,
+					"emailTemplate": {
+						"body": "<p>Hello,</p>\n<p>Your one-time password is: <strong>{OTP}</strong></p>\n<p><i>If you didn't ask for the one-time password, you can ignore this email.</i></p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+						"subject": "OTP for {APP_NAME}"
+					},
+					"enabled": false,
+					"length": 8
+				},
+				"passwordAuth": {
+					"enabled": true,
+					"identityFields": [
+						"email"
+					]
+				},
+				"passwordResetToken": {
+					"duration": 1800
+				},
+				"resetPasswordTemplate": {
+					"body": "<p>Hello,</p>\n<p>Click on the button below to reset your password.</p>\n<p>\n  <a class=\"btn\" href=\"{APP_URL}/_/#/auth/confirm-password-reset/{TOKEN}\" target=\"_blank\" rel=\"noopener\">Reset password</a>\n</p>\n<p><i>If you didn't ask to reset your password, you can ignore this email.</i></p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+					"subject": "Reset your {APP_NAME} password"
+				},
+				"system": false,
+				"type": "auth",
+				"updateRule": "id = @request.auth.id",
+				"verificationTemplate": {
+					"body": "<p>Hello,</p>\n<p>Thank you for joining us at {APP_NAME}.</p>\n<p>Click on the button below to verify your email address.</p>\n<p>\n  <a class=\"btn\" href=\"{APP_URL}/_/#/auth/confirm-verification/{TOKEN}\" target=\"_blank\" rel=\"noopener\">Verify</a>\n</p>\n<p>\n  Thanks,<br/>\n  {APP_NAME} team\n</p>",
+					"subject": "Verify your {APP_NAME} email"
+				},
+				"verificationToken": {
+					"duration": 259200
+				},
+				"viewRule": ""
+			},
+			{
+				"createRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text4262580536",
+						"max": 0,
+						"min": 0,
+						"name": "Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "date3378322619",
+						"max": "",
+						"min": "",
+						"name": "Start_Date",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "date"
+					},
+					{
+						"hidden": false,
+						"id": "date4277894495",
+						"max": "",
+						"min": "",
+						"name": "End_Date",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "date"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "_pb_users_auth_",
+						"hidden": false,
+						"id": "relation1915005571",
+						"maxSelect": 999,
+						"minSelect": 0,
+						"name": "Project_Members",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "bool3087654605",
+						"name": "Completed",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_3853224427",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_sBwDD8TCC6` + "`" + ` ON ` + "`" + `Projects` + "`" + ` (` + "`" + `Name` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Projects",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.id != \"\" && Project_Members.id ?= @request.auth.id && 'viewer' != @request.auth.role",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text810127735",
+						"max": 0,
+						"min": 0,
+						"name": "Domain_Provider",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text4262580536",
+						"max": 0,
+						"min": 0,
+						"name": "Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "date529568325",
+						"max": "",
+						"min": "",
+						"name": "Purchased_Date",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "date"
+					},
+					{
+						"hidden": false,
+						"id": "date2153579294",
+						"max": "",
+						"min": "",
+						"name": "Expiration_Date",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "date"
+					},
+					{
+						"hidden": false,
+						"id": "bool2408796623",
+						"name": "Is_Expired",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "bool1069990619",
+						"name": "Is_Locked",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "bool4032615268",
+						"name": "Auto_Renew",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"hidden": false,
+						"id": "bool4138624602",
+						"name": "Custom_DNS",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_3853224427",
+						"hidden": false,
+						"id": "relation166631649",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Assigned_Project",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "bool2954265716",
+						"name": "Healthy",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3235547528",
+						"max": 0,
+						"min": 0,
+						"name": "Notes",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "select3482204952",
+						"maxSelect": 5,
+						"name": "Tags",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "select",
+						"values": [
+							"Generic",
+							"Admin",
+							"C2",
+							"Email",
+							"Hosting"
+						]
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_3853224427",
+						"hidden": false,
+						"id": "relation1325688256",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Last_Used",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_2415149314",
+						"hidden": false,
+						"id": "relation773890894",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Service",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_3533044203",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_HaCPlW9s2H` + "`" + ` ON ` + "`" + `Domains` + "`" + ` (` + "`" + `Name` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Domains",
+				"system": false,
+				"type": "base",
+				"updateRule": "(@request.auth.id != \"\" && 'viewer' != @request.auth.role) && ('admin' = @request.auth.role || Assigned_Project.Project_Members.id ?= @request.auth.id || Assigned_Project = null)",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": "@request.auth.role = \"admin\"",
+				"deleteRule": "@request.auth.role = \"admin\"",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "select1806832074",
+						"maxSelect": 1,
+						"name": "Provider",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "select",
+						"values": [
+							"Namecheap",
+							"Porkbun",
+							"Cloudflare",
+							"VirusTotal"
+						]
+					},
+					{
+						"hidden": false,
+						"id": "json473154195",
+						"maxSize": 0,
+						"name": "Settings",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "json"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3086987206",
+						"max": 0,
+						"min": 0,
+						"name": "Cron",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3479601132",
+						"max": 0,
+						"min": 0,
+						"name": "Label",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_2415149314",
+				"indexes": [
+					"CREATE INDEX ` + "`" + `idx_a0KchSLOyb` + "`" + ` ON ` + "`" + `Services` + "`" + ` (` + "`" + `Provider` + "`" + `)"
+				],
+				"listRule": "@request.auth.role = \"admin\"",
+				"name": "Services",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.role = \"admin\"",
+				"viewRule": "@request.auth.role = \"admin\""
+			},
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_3533044203",
+						"hidden": false,
+						"id": "relation2684689213",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Domain",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1172049300",
+						"max": 0,
+						"min": 0,
+						"name": "Record_Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1338500628",
+						"max": 0,
+						"min": 0,
+						"name": "Record_Type",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3270727197",
+						"max": 0,
+						"min": 0,
+						"name": "Address",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1166134459",
+						"max": 0,
+						"min": 0,
+						"name": "Host_Id",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_1534621069",
+				"indexes": [],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Domain_Records",
+				"system": false,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"deleteRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3578885000",
+						"max": 0,
+						"min": 0,
+						"name": "Domain",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "number185142749",
+						"max": null,
+						"min": null,
+						"name": "Price",
+						"onlyInt": false,
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3950563313",
+						"max": 0,
+						"min": 0,
+						"name": "Description",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "_pb_users_auth_",
+						"hidden": false,
+						"id": "relation765557111",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "User",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_1084320242",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_5EO6u3q4Hq` + "`" + ` ON ` + "`" + `Domain_Ideas` + "`" + ` (` + "`" + `Domain` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Domain_Ideas",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"deleteRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1579384326",
+						"max": 0,
+						"min": 0,
+						"name": "Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "_pb_users_auth_",
+						"hidden": false,
+						"id": "relation3823579430",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Uploaded_By",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text270487449",
+						"max": 0,
+						"min": 0,
+						"name": "Phishlet",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text18589324",
+						"max": 0,
+						"min": 0,
+						"name": "Notes",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_1947705247",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_LdH4Tj2sEH` + "`" + ` ON ` + "`" + `Phishlets` + "`" + ` (` + "`" + `Name` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Phishlets",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"deleteRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text4262580536",
+						"max": 0,
+						"min": 0,
+						"name": "Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text2484424267",
+						"max": 0,
+						"min": 0,
+						"name": "Example_Subject",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1051532324",
+						"max": 0,
+						"min": 0,
+						"name": "Example_From",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text144386869",
+						"max": 0,
+						"min": 0,
+						"name": "Target_Group",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"convertURLs": false,
+						"hidden": false,
+						"id": "editor787223889",
+						"maxSize": 0,
+						"name": "HTML",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "editor"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1031618853",
+						"max": 0,
+						"min": 0,
+						"name": "Caddy",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3235547528",
+						"max": 0,
+						"min": 0,
+						"name": "Notes",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_1947705247",
+						"hidden": false,
+						"id": "relation3915984335",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Phishlet",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "_pb_users_auth_",
+						"hidden": false,
+						"id": "relation3425129875",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Updated_By",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_136060711",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_PrPkrRRA5p` + "`" + ` ON ` + "`" + `Phishing_Templates` + "`" + ` (` + "`" + `Name` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Phishing_Templates",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"deleteRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_136060711",
+						"hidden": false,
+						"id": "relation2513438518",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Phishing_Template",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text4262580536",
+						"max": 0,
+						"min": 0,
+						"name": "Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3950563313",
+						"max": 0,
+						"min": 0,
+						"name": "Description",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "file2979201658",
+						"maxSelect": 1,
+						"maxSize": 0,
+						"mimeTypes": [],
+						"name": "File",
+						"presentable": false,
+						"protected": true,
+						"required": true,
+						"system": false,
+						"thumbs": [],
+						"type": "file"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "_pb_users_auth_",
+						"hidden": false,
+						"id": "relation4043283027",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Uploaded_By",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_3477349043",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_yBrKUteHuG` + "`" + ` ON ` + "`" + `Artifacts` + "`" + ` (\n  ` + "`" + `Phishing_Template` + "`" + `,\n  ` + "`" + `Name` + "`" + `\n)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Artifacts",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.id != \"\" && 'viewer' != @request.auth.role",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": "@request.auth.id != \"\" && Project.Project_Members.id ?= @request.auth.id && 'viewer' != @request.auth.role",
+				"deleteRule": "@request.auth.id != \"\" && Project.Project_Members.id ?= @request.auth.id && 'viewer' != @request.auth.role",
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_3853224427",
+						"hidden": false,
+						"id": "relation3759073650",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Project",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_136060711",
+						"hidden": false,
+						"id": "relation2513438518",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Phishing_Template",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "_pb_users_auth_",
+						"hidden": false,
+						"id": "relation80448548",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Created_By",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text879953894",
+						"max": 0,
+						"min": 0,
+						"name": "Subject",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text422055502",
+						"max": 0,
+						"min": 0,
+						"name": "From",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "date3101265600",
+						"max": "",
+						"min": "",
+						"name": "Date_Sent",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "date"
+					},
+					{
+						"hidden": false,
+						"id": "number745340569",
+						"max": null,
+						"min": 0,
+						"name": "Emails_Sent",
+						"onlyInt": true,
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number3931167571",
+						"max": null,
+						"min": 0,
+						"name": "Emails_Clicked",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number3527036730",
+						"max": null,
+						"min": 0,
+						"name": "Creds_Submit",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_2620986233",
+				"indexes": [],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Phishing_Metrics",
+				"system": false,
+				"type": "base",
+				"updateRule": "@request.auth.id != \"\" && Project.Project_Members.id ?= @request.auth.id && 'viewer' != @request.auth.role",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 0,
+						"min": 0,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "_clone_3Xfu",
+						"max": 0,
+						"min": 0,
+						"name": "Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "_clone_FG0D",
+						"max": 0,
+						"min": 0,
+						"name": "Target_Group",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "json3302799700",
+						"maxSize": 1,
+						"name": "total_sent",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "json"
+					},
+					{
+						"hidden": false,
+						"id": "json125744358",
+						"maxSize": 1,
+						"name": "total_clicked",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "json"
+					},
+					{
+						"hidden": false,
+						"id": "json4146434133",
+						"maxSize": 1,
+						"name": "total_submit",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "json"
+					}
+				],
+				"id": "pbc_720058035",
+				"indexes": [],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Phishing_Templates_View",
+				"system": false,
+				"type": "view",
+				"updateRule": null,
+				"viewQuery": "SELECT \n  a.id,\n  a.` + "`" + `Name` + "`" + `,\n  a.` + "`" + `Target_Group` + "`" + `,\n  COALESCE(SUM(b.` + "`" + `Emails_Sent` + "`" + `), 0) AS total_sent,\n  COALESCE(SUM(b.` + "`" + `Emails_Clicked` + "`" + `), 0) AS total_clicked,\n  COALESCE(SUM(b.` + "`" + `Creds_Submit` + "`" + `), 0) AS total_submit\nFROM \n  ` + "`" + `Phishing_Templates` + "`" + ` a\nLEFT JOIN \n  ` + "`" + `Phishing_Metrics` + "`" + ` b ON a.id = b.` + "`" + `Phishing_Template` + "`" + `\nGROUP BY \n  a.` + "`" + `Name` + "`" + `",
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text2812878347",
+						"max": 0,
+						"min": 0,
+						"name": "Domain",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": true,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "number2477264054",
+						"max": null,
+						"min": 0,
+						"name": "Votes_Harmless",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number1419265167",
+						"max": null,
+						"min": 0,
+						"name": "Votes_Malicious",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number4127964388",
+						"max": null,
+						"min": 0,
+						"name": "Malicious",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number2862767953",
+						"max": null,
+						"min": 0,
+						"name": "Suspicious",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number1281795943",
+						"max": null,
+						"min": 0,
+						"name": "Undetected",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number1730221461",
+						"max": null,
+						"min": 0,
+						"name": "Harmless",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "number1325157390",
+						"max": null,
+						"min": 0,
+						"name": "Timeout",
+						"onlyInt": true,
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "number"
+					},
+					{
+						"hidden": false,
+						"id": "json2349559495",
+						"maxSize": 0,
+						"name": "Last_Analysis_Results",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "json"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_2154731867",
+				"indexes": [
+					"CREATE UNIQUE INDEX ` + "`" + `idx_66rFHClpdj` + "`" + ` ON ` + "`" + `VirusTotal` + "`" + ` (` + "`" + `Domain` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "VirusTotal",
+				"system": false,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != \"\""
+			},
+			{
+				"createRule": null,
+				"deleteRule": null,
+				"fields": [
+					{
+						"autogeneratePattern": "[a-z0-9]{15}",
+						"hidden": false,
+						"id": "text3208210256",
+						"max": 15,
+						"min": 15,
+						"name": "id",
+						"pattern": "^[a-z0-9]+$",
+						"presentable": false,
+						"primaryKey": true,
+						"required": true,
+						"system": true,
+						"type": "text"
+					},
+					{
+						"cascadeDelete": true,
+						"collectionId": "pbc_3533044203",
+						"hidden": false,
+						"id": "relation2684689213",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Domain",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1806832074",
+						"max": 0,
+						"min": 0,
+						"name": "Provider",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "select1080068516",
+						"maxSelect": 1,
+						"name": "Action",
+						"presentable": false,
+						"required": true,
+						"system": false,
+						"type": "select",
+						"values": [
+							"added",
+							"modified",
+							"removed"
+						]
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text2637877051",
+						"max": 0,
+						"min": 0,
+						"name": "Record_Name",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"autogeneratePattern": "",
+						"hidden": false,
+						"id": "text1338500628",
+						"max": 0,
+						"min": 0,
+						"name": "Record_Type",
+						"pattern": "",
+						"presentable": false,
+						"primaryKey": false,
+						"required": false,
+						"system": false,
+						"type": "text"
+					},
+					{
+						"hidden": false,
+						"id": "json4174593909",
+						"maxSize": 0,
+						"name": "Old_Value",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "json"
+					},
+					{
+						"hidden": false,
+						"id": "json2798761550",
+						"maxSize": 0,
+						"name": "New_Value",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "json"
+					},
+					{
+						"hidden": false,
+						"id": "bool2309941288",
+						"name": "Drift",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "bool"
+					},
+					{
+						"cascadeDelete": false,
+						"collectionId": "pbc_3853224427",
+						"hidden": false,
+						"id": "relation4255722112",
+						"maxSelect": 1,
+						"minSelect": 0,
+						"name": "Assigned_Project",
+						"presentable": false,
+						"required": false,
+						"system": false,
+						"type": "relation"
+					},
+					{
+						"hidden": false,
+						"id": "autodate2990389176",
+						"name": "created",
+						"onCreate": true,
+						"onUpdate": false,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					},
+					{
+						"hidden": false,
+						"id": "autodate3332085495",
+						"name": "updated",
+						"onCreate": true,
+						"onUpdate": true,
+						"presentable": false,
+						"system": false,
+						"type": "autodate"
+					}
+				],
+				"id": "pbc_900036572",
+				"indexes": [
+					"CREATE INDEX ` + "`" + `idx_qF6x2LsIcc` + "`" + ` ON ` + "`" + `Domain_Record_Changes` + "`" + ` (` + "`" + `Domain` + "`" + `)"
+				],
+				"listRule": "@request.auth.id != \"\"",
+				"name": "Domain_Record_Changes",
+				"system": false,
+				"type": "base",
+				"updateRule": null,
+				"viewRule": "@request.auth.id != \"\""
+			}
+		]`
+
+		return app.ImportCollectionsByMarshaledJSON([]byte(jsonData), false)
+	}, func(app core.App) error {
+		return nil
+	})
+}
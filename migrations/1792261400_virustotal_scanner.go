@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// The results saved before the scanner was recorded all came from VirusTotal
+func init() {
+	m.Register(func(app core.App) error {
+		for _, collection := range []string{"VirusTotal", "VirusTotal_Scans"} {
+			_, err := app.DB().NewQuery("UPDATE {{" + collection + "}} SET [[Scanner]] = 'VirusTotal' WHERE [[Scanner]] = ''").Execute()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Chat webhook of Slack, Discord or Microsoft Teams, they only differ in the payload
+type chat struct {
+	url    string
+	name   string
+	format func(event Event) any
+	client *http.Client
+}
+
+func (c *chat) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(c.format(event))
+	if err != nil {
+		return err
+	}
+	return post(ctx, c.client, c.name, c.url, body, nil)
+}
+
+// Slack incoming webhook message using mrkdwn
+func slackPayload(event Event) any {
+	var b strings.Builder
+	b.WriteString("*" + event.Subject + "*\n" + event.Message)
+	for _, f := range event.Fields {
+		b.WriteString("\n• *" + f.Name + ":* " + f.Value)
+	}
+	return map[string]any{"text": b.String()}
+}
+
+// Discord webhook message with an embed, the fields are shown inline
+func discordPayload(event Event) any {
+	fields := []map[string]any{}
+	for _, f := range event.Fields {
+		fields = append(fields, map[string]any{"name": f.Name, "value": f.Value, "inline": true})
+	}
+
+	return map[string]any{
+		"embeds": []map[string]any{{
+			"title":       event.Subject,
+			"description": event.Message,
+			"fields":      fields,
+			"timestamp":   event.Time.UTC().Format(time.RFC3339),
+		}},
+	}
+}
+
+// Microsoft Teams workflow webhook message with an Adaptive Card
+func teamsPayload(event Event) any {
+	facts := []map[string]string{}
+	for _, f := range event.Fields {
+		facts = append(facts, map[string]string{"title": f.Name, "value": f.Value})
+	}
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body": []map[string]any{
+					{"type": "TextBlock", "text": event.Subject, "weight": "Bolder", "size": "Medium", "wrap": true},
+					{"type": "TextBlock", "text": event.Message, "wrap": true},
+					{"type": "FactSet", "facts": facts},
+				},
+			},
+		}},
+	}
+}
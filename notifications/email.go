@@ -0,0 +1,59 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/mail"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// Email channel that sends the event with the mail settings of PocketBase
+type email struct {
+	to   []mail.Address
+	mail Mail
+}
+
+// Create an email channel for a comma separated list of recipients
+func newEmail(to string, m Mail) (*email, error) {
+	if m.Client == nil {
+		return nil, errors.New("no mail client")
+	}
+
+	addresses, err := mail.ParseAddressList(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []mail.Address
+	for _, a := range addresses {
+		recipients = append(recipients, *a)
+	}
+	return &email{to: recipients, mail: m}, nil
+}
+
+func (e *email) Send(ctx context.Context, event Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("<p>" + html.EscapeString(event.Message) + "</p>")
+	if len(event.Fields) > 0 {
+		b.WriteString("<table>")
+		for _, f := range event.Fields {
+			b.WriteString("<tr><th align=\"left\">" + html.EscapeString(f.Name) + "</th><td>" + html.EscapeString(f.Value) + "</td></tr>")
+		}
+		b.WriteString("</table>")
+	}
+
+	return e.mail.Client.Send(&mailer.Message{
+		From:    e.mail.From,
+		To:      e.to,
+		Subject: "[RedCompass] " + event.Subject,
+		HTML:    b.String(),
+		Text:    event.Text(),
+	})
+}
@@ -0,0 +1,154 @@
+// Package notifications sends RedCompass events to chat webhooks, signed JSON webhooks and email.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+// Events that notification rules can subscribe to
+const (
+	EventDomainExpiring      = "domain_expiring"
+	EventDomainUnhealthy     = "domain_unhealthy"
+	EventDomainBurned        = "domain_burned"
+	EventSyncFailed          = "sync_failed"
+	EventDetectionsIncreased = "detections_increased"
+	EventProjectCompleted    = "project_completed"
+	EventCategoryChanged     = "category_changed"
+	EventRecordDrift         = "record_drift"
+)
+
+// Types of notification channels
+const (
+	ChannelSlack   = "slack"
+	ChannelDiscord = "discord"
+	ChannelTeams   = "teams"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+// A detail of an event, e.g. the domain name or the expiration date
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Event sent to the channels of the rules that subscribe to it
+type Event struct {
+	Type    string    `json:"event"`
+	Subject string    `json:"subject"`
+	Message string    `json:"message"`
+	Fields  []Field   `json:"fields"`
+	Time    time.Time `json:"time"`
+
+	// Used to send an event only once per rule, e.g. the domain and the expiration date
+	Key string `json:"key,omitempty"`
+}
+
+// Channel delivers events to a destination
+type Channel interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// Settings of a channel saved on the Notification_Channels record
+type Settings struct {
+	WebhookURL string `json:"webhookUrl"`
+	Secret     string `json:"secret"`
+	To         string `json:"to"`
+}
+
+// Mailer and sender address used by the email channels
+type Mail struct {
+	Client mailer.Mailer
+	From   mail.Address
+}
+
+// NewChannel creates a channel from its type and settings
+func NewChannel(channelType string, settings string, m Mail) (Channel, error) {
+	var s Settings
+	if settings != "" {
+		if err := json.Unmarshal([]byte(settings), &s); err != nil {
+			return nil, err
+		}
+	}
+
+	switch channelType {
+	case ChannelSlack, ChannelDiscord, ChannelTeams, ChannelWebhook:
+		if !strings.HasPrefix(s.WebhookURL, "https://") && !strings.HasPrefix(s.WebhookURL, "http://") {
+			return nil, errors.New("invalid webhook URL")
+		}
+	}
+
+	switch channelType {
+	case ChannelSlack:
+		return &chat{url: s.WebhookURL, name: "Slack", format: slackPayload, client: slackClient}, nil
+	case ChannelDiscord:
+		return &chat{url: s.WebhookURL, name: "Discord", format: discordPayload, client: discordClient}, nil
+	case ChannelTeams:
+		return &chat{url: s.WebhookURL, name: "Teams", format: teamsPayload, client: teamsClient}, nil
+	case ChannelWebhook:
+		return &webhook{url: s.WebhookURL, secret: s.Secret, client: webhookClient}, nil
+	case ChannelEmail:
+		return newEmail(s.To, m)
+	}
+
+	return nil, errors.New("invalid channel type")
+}
+
+// HTTP clients of the webhooks, their requests show up in the provider metrics
+var (
+	slackClient   = newClient("Slack")
+	discordClient = newClient("Discord")
+	teamsClient   = newClient("Teams")
+	webhookClient = newClient("Webhook")
+)
+
+func newClient(name string) *http.Client {
+	return providers.NewHTTPClient(name, providers.ClientOptions{Timeout: 30 * time.Second})
+}
+
+// Post a JSON body to a webhook. The request is retried on rate limits and temporary failures,
+// a notification sent twice is better than a missed one.
+func post(ctx context.Context, client *http.Client, name string, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(providers.Idempotent(ctx), http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return providers.FromRequestError(name, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return providers.FromResponse(name, res, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// Plain text of an event with the fields on their own line
+func (e Event) Text() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, "\n%s: %s", f.Name, f.Value)
+	}
+	return b.String()
+}
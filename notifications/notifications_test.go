@@ -0,0 +1,108 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testEvent = Event{
+	Type:    EventDomainExpiring,
+	Subject: "Domain example.com expires in 3 days",
+	Message: "The domain example.com expires on 2026-01-04.",
+	Fields:  []Field{{Name: "Domain", Value: "example.com"}},
+	Time:    time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC),
+	Key:     "abc:2026-01-04",
+}
+
+func TestWebhookSignature(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	ch, err := NewChannel(ChannelWebhook, `{"webhookUrl":"`+srv.URL+`","secret":"s3cret"}`, Mail{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := strconv.FormatInt(testEvent.Time.Unix(), 10)
+	if got.Header.Get(EventHeader) != EventDomainExpiring || got.Header.Get(TimestampHeader) != timestamp {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if want := Sign("s3cret", timestamp, body); got.Header.Get(SignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", got.Header.Get(SignatureHeader), want)
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil || event.Subject != testEvent.Subject || event.Key != testEvent.Key {
+		t.Errorf("unexpected body %s", body)
+	}
+}
+
+func TestChatPayloads(t *testing.T) {
+	tests := map[string]string{
+		ChannelSlack:   "text",
+		ChannelDiscord: "embeds",
+		ChannelTeams:   "attachments",
+	}
+
+	for channelType, key := range tests {
+		t.Run(channelType, func(t *testing.T) {
+			var payload map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&payload)
+			}))
+			defer srv.Close()
+
+			ch, err := NewChannel(channelType, `{"webhookUrl":"`+srv.URL+`"}`, Mail{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ch.Send(context.Background(), testEvent); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := payload[key]; !ok {
+				t.Errorf("payload %v is missing %q", payload, key)
+			}
+		})
+	}
+}
+
+func TestSendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	ch, err := NewChannel(ChannelSlack, `{"webhookUrl":"`+srv.URL+`"}`, Mail{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(context.Background(), testEvent); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNewChannelInvalid(t *testing.T) {
+	if _, err := NewChannel(ChannelSlack, `{"webhookUrl":"ftp://example.com"}`, Mail{}); err == nil {
+		t.Error("expected an error for an invalid webhook URL")
+	}
+	if _, err := NewChannel(ChannelEmail, `{"to":"admin@example.com"}`, Mail{}); err == nil {
+		t.Error("expected an error without a mail client")
+	}
+	if _, err := NewChannel("sms", `{}`, Mail{}); err == nil {
+		t.Error("expected an error for an invalid channel type")
+	}
+}
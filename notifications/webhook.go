@@ -0,0 +1,52 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Headers of the generic webhook requests
+const (
+	EventHeader     = "X-RedCompass-Event"
+	TimestampHeader = "X-RedCompass-Timestamp"
+	SignatureHeader = "X-RedCompass-Signature"
+)
+
+// Generic webhook that posts the event as JSON. When a secret is set the request is signed with
+// HMAC-SHA256 over "<timestamp>.<body>" and the signature is sent as "sha256=<hex>".
+type webhook struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func (w *webhook) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(event.Time.Unix(), 10)
+	headers := map[string]string{
+		EventHeader:     event.Type,
+		TimestampHeader: timestamp,
+	}
+	if w.secret != "" {
+		headers[SignatureHeader] = Sign(w.secret, timestamp, body)
+	}
+
+	return post(ctx, w.client, "Webhook", w.url, body, headers)
+}
+
+// Sign a webhook body, receivers compute the same value to check the request came from RedCompass
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/notifications"
+	"github.com/lum8rjack/redcompass/services/types"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Cron job that checks for expiring domains every day
+const (
+	expiringDomainsJobID = "notifications-expiring-domains"
+	expiringDomainsCron  = "0 8 * * *"
+)
+
+// Days before the expiration date a domain_expiring rule notifies when it does not set Expiring_Days
+const defaultExpiringDays = 30
+
+// Status of a notification delivery
+const (
+	DeliverySent   = "sent"
+	DeliveryFailed = "failed"
+)
+
+// Send an event to the channels of the enabled rules that subscribe to it
+func Notify(event notifications.Event) {
+	rules, err := app.FindAllRecords("Notification_Rules",
+		dbx.HashExp{"Event": event.Type, "Enabled": true},
+	)
+	if err != nil {
+		app.Logger().Error("NOTIFY:"+event.Type, "function", "app.FindAllRecords", "error", err.Error())
+		return
+	}
+
+	for _, rule := range rules {
+		notifyRule(rule, event)
+	}
+}
+
+// Send an event to the enabled channels of a rule. The deliveries run in the background and are
+// logged to Notification_Deliveries, a channel that already received an event with the same key
+// for the rule is skipped.
+func notifyRule(rule *core.Record, event notifications.Event) {
+	msg := "NOTIFY:" + event.Type
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	channels, err := app.FindRecordsByIds("Notification_Channels", rule.GetStringSlice("Channels"))
+	if err != nil {
+		app.Logger().Error(msg, "ruleID", rule.Id, "function", "app.FindRecordsByIds", "error", err.Error())
+		return
+	}
+
+	for _, channel := range channels {
+		if !channel.GetBool("Enabled") || alreadyDelivered(rule, channel, event.Key) {
+			continue
+		}
+
+		runningJobs.Add(1)
+		go func() {
+			defer runningJobs.Done()
+			deliver(rule, channel, event)
+		}()
+	}
+}
+
+// Check if an event with the key was already sent to the channel for the rule
+func alreadyDelivered(rule *core.Record, channel *core.Record, key string) bool {
+	if key == "" {
+		return false
+	}
+
+	_, err := app.FindFirstRecordByFilter("Notification_Deliveries",
+		"Rule = {:rule} && Channel = {:channel} && Key = {:key} && Status = {:status}",
+		dbx.Params{"rule": rule.Id, "channel": channel.Id, "key": key, "status": DeliverySent},
+	)
+	return err == nil
+}
+
+// Send an event to a channel, long rate limits are retried once the Retry-After has passed
+func deliver(rule *core.Record, channel *core.Record, event notifications.Event) {
+	msg := "NOTIFY:" + event.Type + " " + channel.GetString("Name")
+	attempts := 0
+
+	settings, err := GetServiceSettings(channel)
+	if err == nil {
+		var ch notifications.Channel
+		ch, err = notifications.NewChannel(channel.GetString("Type"), settings, notifications.Mail{
+			Client: app.NewMailClient(),
+			From:   mail.Address{Name: app.Settings().Meta.SenderName, Address: app.Settings().Meta.SenderAddress},
+		})
+		if err == nil {
+			err = retryProviderCall(jobsCtx, msg, func() error {
+				attempts++
+				return ch.Send(jobsCtx, event)
+			})
+		}
+	}
+
+	status := DeliverySent
+	if err != nil {
+		status = DeliveryFailed
+		app.Logger().Error(msg, "ruleID", rule.Id, "channelID", channel.Id, "attempts", attempts, "error", err.Error())
+	} else {
+		app.Logger().Info(msg, "ruleID", rule.Id, "channelID", channel.Id, "status", status)
+	}
+
+	logDelivery(rule, channel, event, status, attempts, err)
+}
+
+// Add a delivery attempt to the Notification_Deliveries log
+func logDelivery(rule *core.Record, channel *core.Record, event notifications.Event, status string, attempts int, deliveryErr error) {
+	collection, err := app.FindCollectionByNameOrId("Notification_Deliveries")
+	if err != nil {
+		app.Logger().Error("NOTIFY:"+event.Type, "function", "app.FindCollectionByNameOrId", "error", err.Error())
+		return
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("Rule", rule.Id)
+	record.Set("Channel", channel.Id)
+	record.Set("Event", event.Type)
+	record.Set("Subject", event.Subject)
+	record.Set("Key", event.Key)
+	record.Set("Status", status)
+	record.Set("Attempts", attempts)
+	if deliveryErr != nil {
+		record.Set("Error", deliveryErr.Error())
+	}
+
+	if err := app.Save(record); err != nil {
+		app.Logger().Error("NOTIFY:"+event.Type, "function", "app.Save", "error", err.Error())
+	}
+}
+
+// Add the cron job that notifies about expiring domains
+func AddExpiringDomainsCronJob() {
+	app.Cron().MustAdd(expiringDomainsJobID, expiringDomainsCron, NotifyExpiringDomains)
+}
+
+// Notify the domain_expiring rules about the domains that expire within the rule's number of days.
+// Expired domains and domains removed from the provider are skipped, and each domain is only
+// sent once per expiration date.
+func NotifyExpiringDomains() {
+	msg := "NOTIFY:" + notifications.EventDomainExpiring
+
+	rules, err := app.FindAllRecords("Notification_Rules",
+		dbx.HashExp{"Event": notifications.EventDomainExpiring, "Enabled": true},
+	)
+	if err != nil {
+		app.Logger().Error(msg, "function", "app.FindAllRecords", "error", err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, rule := range rules {
+		days := rule.GetInt("Expiring_Days")
+		if days <= 0 {
+			days = defaultExpiringDays
+		}
+
+		domains, err := app.FindAllRecords("Domains",
+			dbx.Between("Expiration_Date", now, now.AddDate(0, 0, days)),
+			dbx.HashExp{"Is_Expired": false, "Removed_From_Provider": false},
+		)
+		if err != nil {
+			app.Logger().Error(msg, "ruleID", rule.Id, "function", "app.FindAllRecords", "error", err.Error())
+			continue
+		}
+
+		for _, domain := range domains {
+			expires := domain.GetDateTime("Expiration_Date").Time()
+			daysLeft := int(expires.Sub(now).Hours() / 24)
+
+			notifyRule(rule, notifications.Event{
+				Type:    notifications.EventDomainExpiring,
+				Subject: "Domain " + domain.GetString("Name") + " expires in " + pluralize(daysLeft, "day"),
+				Message: "The domain " + domain.GetString("Name") + " expires on " + expires.Format(time.DateOnly) + ".",
+				Fields: []notifications.Field{
+					{Name: "Domain", Value: domain.GetString("Name")},
+					{Name: "Expires", Value: expires.Format(time.DateOnly)},
+					{Name: "Auto Renew", Value: strconv.FormatBool(domain.GetBool("Auto_Renew"))},
+					{Name: "Provider", Value: domain.GetString("Domain_Provider")},
+				},
+				Key: domain.Id + ":" + expires.Format(time.DateOnly),
+			})
+		}
+	}
+}
+
+// Notify when a domain is no longer healthy
+func notifyDomainUnhealthy(domain *core.Record) {
+	fields := domainFields(domain)
+	if reason := domain.GetString("Unhealthy_Reason"); reason != "" {
+		fields = append(fields, notifications.Field{Name: "Reason", Value: reason})
+	}
+
+	Notify(notifications.Event{
+		Type:    notifications.EventDomainUnhealthy,
+		Subject: "Domain " + domain.GetString("Name") + " is unhealthy",
+		Message: "The domain " + domain.GetString("Name") + " was marked as unhealthy.",
+		Fields:  fields,
+	})
+}
+
+// Notify when the health policy marks a domain assigned to an active project as unhealthy
+func notifyDomainBurned(domain *core.Record, project *core.Record) {
+	Notify(notifications.Event{
+		Type:    notifications.EventDomainBurned,
+		Subject: "Domain " + domain.GetString("Name") + " burned on project " + project.GetString("Name"),
+		Message: "The domain " + domain.GetString("Name") + " is assigned to the active project " + project.GetString("Name") + " and was marked as unhealthy by the health policy.",
+		Fields: append(domainFields(domain),
+			notifications.Field{Name: "Reason", Value: domain.GetString("Unhealthy_Reason")},
+		),
+		Key: domain.Id + ":" + domain.GetDateTime("Unhealthy_Date").String(),
+	})
+}
+
+// Notify when a domain sync failed
+func notifySyncFailed(service *core.Record, runErrors []JobRunError) {
+	fields := []notifications.Field{{Name: "Service", Value: serviceLogName(service)}}
+	if len(runErrors) > 0 {
+		last := runErrors[len(runErrors)-1]
+		fields = append(fields, notifications.Field{Name: "Error", Value: last.Function + ": " + last.Error})
+	}
+
+	Notify(notifications.Event{
+		Type:    notifications.EventSyncFailed,
+		Subject: "Domain sync failed for " + serviceLogName(service),
+		Message: "The domain sync for " + serviceLogName(service) + " failed.",
+		Fields:  fields,
+	})
+}
+
+// Notify when VirusTotal reports more malicious or suspicious detections for a domain
+func notifyDetectionsIncreased(domainName string, previous int, current int) {
+	fields := []notifications.Field{
+		{Name: "Domain", Value: domainName},
+		{Name: "Detections", Value: fmt.Sprintf("%d (was %d)", current, previous)},
+	}
+	if domain, err := app.FindFirstRecordByData("Domains", "Name", domainName); err == nil {
+		fields = domainFields(domain)
+		fields = append(fields, notifications.Field{Name: "Detections", Value: fmt.Sprintf("%d (was %d)", current, previous)})
+	}
+
+	Notify(notifications.Event{
+		Type:    notifications.EventDetectionsIncreased,
+		Subject: "VirusTotal detections increased for " + domainName,
+		Message: fmt.Sprintf("VirusTotal reports %s for %s, up from %d.", pluralize(current, "malicious or suspicious detection"), domainName, previous),
+		Fields:  fields,
+	})
+}
+
+// Notify when a vendor changed the category of a domain
+func notifyCategoryChanged(domain *core.Record, vendor string, previous string, category string) {
+	Notify(notifications.Event{
+		Type:    notifications.EventCategoryChanged,
+		Subject: vendor + " changed the category of " + domain.GetString("Name"),
+		Message: vendor + " now categorises " + domain.GetString("Name") + " as " + category + ", it was " + previous + ".",
+		Fields: append(domainFields(domain),
+			notifications.Field{Name: "Vendor", Value: vendor},
+			notifications.Field{Name: "Category", Value: category + " (was " + previous + ")"},
+		),
+	})
+}
+
+// Notify when the domain sync changed the DNS records of a domain assigned to a project
+func notifyRecordDrift(domain *core.Record, provider string, changes []RecordChange) {
+	fields := domainFields(domain)
+	for _, change := range changes {
+		fields = append(fields, notifications.Field{
+			Name:  strings.ToUpper(change.Action[:1]) + change.Action[1:],
+			Value: recordChangeSummary(change),
+		})
+	}
+
+	Notify(notifications.Event{
+		Type:    notifications.EventRecordDrift,
+		Subject: "DNS records changed on " + domain.GetString("Name"),
+		Message: "The " + provider + " sync found " + pluralize(len(changes), "DNS record change") + " on " + domain.GetString("Name") + ", which is assigned to a project.",
+		Fields:  fields,
+	})
+}
+
+// Describe a record change, e.g. "www A 1.2.3.4 -> 5.6.7.8"
+func recordChangeSummary(change RecordChange) string {
+	r := change.New
+	if r == nil {
+		r = change.Old
+	}
+
+	summary := r.Name + " " + r.Type + " "
+	switch {
+	case change.Old == nil:
+		return summary + recordValue(change.New)
+	case change.New == nil:
+		return summary + recordValue(change.Old)
+	}
+	return summary + recordValue(change.Old) + " -> " + recordValue(change.New)
+}
+
+// Value of a record with its priority, TTL and proxied status
+func recordValue(r *types.Record) string {
+	value := r.Address
+	if r.Priority != "" {
+		value = r.Priority + " " + value
+	}
+	if r.TTL != "" {
+		value += " (TTL " + r.TTL + ")"
+	}
+	if r.Proxied {
+		value += " (proxied)"
+	}
+	return value
+}
+
+// Notify when a project is completed
+func notifyProjectCompleted(project *core.Record, domainsReleased int) {
+	Notify(notifications.Event{
+		Type:    notifications.EventProjectCompleted,
+		Subject: "Project " + project.GetString("Name") + " completed",
+		Message: "The project " + project.GetString("Name") + " was completed and its domains were unassigned.",
+		Fields: []notifications.Field{
+			{Name: "Project", Value: project.GetString("Name")},
+			{Name: "Domains Released", Value: strconv.Itoa(domainsReleased)},
+		},
+	})
+}
+
+// Fields shared by the domain events
+func domainFields(domain *core.Record) []notifications.Field {
+	fields := []notifications.Field{
+		{Name: "Domain", Value: domain.GetString("Name")},
+		{Name: "Provider", Value: domain.GetString("Domain_Provider")},
+	}
+
+	if projectID := domain.GetString("Assigned_Project"); projectID != "" {
+		if project, err := app.FindRecordById("Projects", projectID); err == nil {
+			fields = append(fields, notifications.Field{Name: "Project", Value: project.GetString("Name")})
+		}
+	}
+	return fields
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return strconv.Itoa(n) + " " + word + "s"
+}
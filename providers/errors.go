@@ -0,0 +1,127 @@
+// Package providers holds the code shared by the registrar services and the scanners.
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Kinds of errors returned by the services and scanners, use errors.Is to check the kind
+var (
+	ErrAuth           = errors.New("authentication failed")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrNotFound       = errors.New("not found")
+	ErrTransient      = errors.New("temporary failure")
+	ErrQuotaExhausted = errors.New("quota exhausted")
+)
+
+// Error returned by a provider with the kind of failure and, when rate limited, how long to wait
+type Error struct {
+	Provider   string
+	Kind       error
+	RetryAfter time.Duration
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := e.Provider + ": " + e.Kind.Error()
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the kind of the error so errors.Is(err, providers.ErrAuth) works
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// NewError creates a new provider error of the given kind
+func NewError(provider string, kind error, message string) *Error {
+	return &Error{Provider: provider, Kind: kind, Message: message}
+}
+
+// RateLimited creates a new rate limited error, retryAfter is 0 if the provider did not say
+func RateLimited(provider string, retryAfter time.Duration, message string) *Error {
+	return &Error{Provider: provider, Kind: ErrRateLimited, RetryAfter: retryAfter, Message: message}
+}
+
+// FromResponse creates an error from an unsuccessful HTTP response. Status codes that do not
+// match a kind return a plain error with the status.
+func FromResponse(provider string, resp *http.Response, message string) error {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return NewError(provider, ErrAuth, message)
+	case resp.StatusCode == http.StatusNotFound:
+		return NewError(provider, ErrNotFound, message)
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return RateLimited(provider, ParseRetryAfter(resp.Header.Get("Retry-After")), message)
+	case resp.StatusCode >= 500:
+		return NewError(provider, ErrTransient, message)
+	}
+
+	if message == "" {
+		return fmt.Errorf("%s: %s", provider, resp.Status)
+	}
+	return fmt.Errorf("%s: %s: %s", provider, resp.Status, message)
+}
+
+// FromRequestError classifies an error from sending a request. Timeouts and network errors are
+// transient, anything else, including a cancelled context, is returned as it is.
+func FromRequestError(provider string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return &Error{Provider: provider, Kind: ErrTransient, Err: err}
+	}
+	return err
+}
+
+// ParseRetryAfter parses a Retry-After header in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// RetryAfter returns how long to wait before retrying if the provider said
+func RetryAfter(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
+
+// IsRetryable reports whether the request can be retried later
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTransient)
+}
@@ -0,0 +1,169 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Defaults for the provider HTTP clients
+const (
+	DefaultTimeout      = 2 * time.Minute
+	DefaultMaxRetries   = 3
+	DefaultMaxRetryWait = time.Minute
+	baseRetryWait       = time.Second
+)
+
+// Options for a provider HTTP client, zero values use the defaults
+type ClientOptions struct {
+	// Timeout for a request including its retries
+	Timeout time.Duration
+
+	// Number of times an idempotent request is retried
+	MaxRetries int
+
+	// Longest time to wait before a retry. A longer Retry-After is returned to the caller
+	// as a rate limited error instead of blocking the request.
+	MaxRetryWait time.Duration
+
+	// Status codes that are retried on top of 429 and 5xx
+	RetryStatuses []int
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks the requests sent with the context as safe to retry. GET, HEAD, OPTIONS,
+// PUT and DELETE requests are always retried, APIs that use POST to read data can use this.
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// NewHTTPClient returns the HTTP client shared by the services and scanners. It times out
+// slow connections, retries idempotent requests with jittered exponential backoff, honours
+// Retry-After and records metrics for the provider.
+func NewHTTPClient(provider string, options ClientOptions) *http.Client {
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DefaultMaxRetries
+	}
+	if options.MaxRetryWait == 0 {
+		options.MaxRetryWait = DefaultMaxRetryWait
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = 10 * time.Second
+	base.ResponseHeaderTimeout = 30 * time.Second
+
+	return &http.Client{
+		Timeout: options.Timeout,
+		Transport: &Transport{
+			Provider: provider,
+			Base:     base,
+			Options:  options,
+		},
+	}
+}
+
+// Transport retries failed requests and records metrics for a provider
+type Transport struct {
+	Provider string
+	Base     http.RoundTripper
+	Options  ClientOptions
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(req)
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err := t.Base.RoundTrip(r)
+		recordRequest(t.Provider, resp, err, time.Since(start))
+
+		if !retryable || attempt >= t.Options.MaxRetries || !t.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		wait := retryWait(attempt, resp)
+		if wait > t.Options.MaxRetryWait {
+			return resp, err
+		}
+
+		// Drain the body so the connection can be reused
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		recordRetry(t.Provider)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Check if the request can be retried
+func isIdempotent(req *http.Request) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	idempotent, _ := req.Context().Value(idempotentKey{}).(bool)
+	return idempotent
+}
+
+// Check if the response or error should be retried
+func (t *Transport) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return resp.StatusCode != http.StatusNotImplemented
+	}
+
+	return slices.Contains(t.Options.RetryStatuses, resp.StatusCode)
+}
+
+// Get the time to wait before the next attempt, using the Retry-After header if there is one
+// or exponential backoff with jitter
+func retryWait(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait := ParseRetryAfter(resp.Header.Get("Retry-After")); wait > 0 {
+			return wait
+		}
+	}
+
+	wait := baseRetryWait << attempt
+	return wait/2 + rand.N(wait/2+1)
+}
@@ -0,0 +1,439 @@
+// Package jsproviders lets pb_hooks files register registrars and scanners written in JavaScript.
+//
+// A hooks file calls registerProvider at the top level with the name, kind, settings JSON schema
+// and the functions of the provider. The functions receive the settings of the Services record
+// and are adapted to types.Service or types.Scanner:
+//
+//	registerProvider({
+//	    name: "Acme",
+//	    kind: "registrar",
+//	    settings: { type: "object", properties: { apiKey: { type: "string", title: "API Key", format: "password" } }, required: ["apiKey"] },
+//	    getDomains: (settings) => [{ name: "example.com", expires: "2027-01-01", autoRenew: true }],
+//	    getDomainRecords: (settings, domain) => [{ id: "1", name: "www", type: "A", address: "192.0.2.1", ttl: 300 }],
+//	})
+//
+// Registrars need getDomains and getDomainRecords and can also define createRecord, updateRecord
+// and deleteRecord. Scanners need getResults, which returns the analysis stats of a domain in the
+// same format as the VirusTotal scanner, and can also define validateKey and getDailyQuotaRemaining.
+//
+// Throwing an object with a status, for example { status: 429, retryAfter: 60, message: "..." },
+// classifies the error like an HTTP response so rate limits and authentication failures are
+// handled the same way as the Go providers.
+package jsproviders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/lum8rjack/redcompass/providers"
+	scannertypes "github.com/lum8rjack/redcompass/scanners/types"
+	servicetypes "github.com/lum8rjack/redcompass/services/types"
+)
+
+// Functions a provider of each kind must define
+var requiredFunctions = map[providers.Kind][]string{
+	providers.KindRegistrar: {"getDomains", "getDomainRecords"},
+	providers.KindScanner:   {"getResults"},
+}
+
+// Date formats accepted for the created and expires dates of a domain
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", time.DateOnly}
+
+// Bind adds the registerProvider function to a VM, it is used as the jsvm OnInit function
+func Bind(vm *goja.Runtime) {
+	// The VM is not safe for concurrent use, the calls of every provider registered on it are serialized
+	mu := &sync.Mutex{}
+
+	vm.Set("registerProvider", func(call goja.FunctionCall) goja.Value {
+		p, err := newProvider(vm, mu, call.Argument(0))
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+
+		if err := providers.Register(p); err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return goja.Undefined()
+	})
+}
+
+// Provider defined in JavaScript, the functions are called on the VM that registered it
+type jsProvider struct {
+	vm        *goja.Runtime
+	mu        *sync.Mutex
+	name      string
+	functions map[string]goja.Callable
+}
+
+// Create the registry entry for a provider definition
+func newProvider(vm *goja.Runtime, mu *sync.Mutex, definition goja.Value) (providers.Provider, error) {
+	if goja.IsUndefined(definition) || goja.IsNull(definition) {
+		return providers.Provider{}, errors.New("registerProvider needs a provider definition")
+	}
+	object := definition.ToObject(vm)
+
+	p := &jsProvider{vm: vm, mu: mu, name: toString(exported(object, "name")), functions: map[string]goja.Callable{}}
+	kind := providers.Kind(toString(exported(object, "kind")))
+
+	for _, key := range object.Keys() {
+		if fn, ok := goja.AssertFunction(object.Get(key)); ok {
+			p.functions[key] = fn
+		}
+	}
+	for _, name := range requiredFunctions[kind] {
+		if _, ok := p.functions[name]; !ok {
+			return providers.Provider{}, fmt.Errorf("%s %s must define %s", kind, p.name, name)
+		}
+	}
+
+	var schema json.RawMessage
+	if v := exported(object, "settings"); v != nil {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return providers.Provider{}, fmt.Errorf("invalid settings schema for %s: %w", p.name, err)
+		}
+		schema = b
+	}
+
+	provider := providers.Provider{Name: p.name, Kind: kind, Settings: schema}
+	switch kind {
+	case providers.KindRegistrar:
+		provider.NewService = func(settings string) (servicetypes.Service, error) {
+			s, err := parseSettings(settings)
+			if err != nil {
+				return nil, err
+			}
+			return &Service{provider: p, settings: s}, nil
+		}
+	case providers.KindScanner:
+		provider.NewScanner = func(settings string) (scannertypes.Scanner, error) {
+			s, err := parseSettings(settings)
+			if err != nil {
+				return nil, err
+			}
+			return &Scanner{provider: p, settings: s}, nil
+		}
+	}
+	return provider, nil
+}
+
+func parseSettings(settings string) (map[string]any, error) {
+	s := map[string]any{}
+	if settings == "" {
+		return s, nil
+	}
+	if err := json.Unmarshal([]byte(settings), &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Check if the provider defines a function
+func (p *jsProvider) has(function string) bool {
+	_, ok := p.functions[function]
+	return ok
+}
+
+// Call a function of the provider and export the value it returns. The VM is interrupted when
+// the context is cancelled.
+func (p *jsProvider) call(ctx context.Context, function string, args ...any) (any, error) {
+	fn, ok := p.functions[function]
+	if !ok {
+		return nil, fmt.Errorf("%s does not support %s", p.name, function)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		p.vm.Interrupt(ctx.Err())
+	})
+	defer func() {
+		stop()
+		p.vm.ClearInterrupt()
+	}()
+
+	values := make([]goja.Value, len(args))
+	for i, arg := range args {
+		values[i] = p.vm.ToValue(arg)
+	}
+
+	result, err := fn(goja.Undefined(), values...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, p.classify(err)
+	}
+	if result == nil || goja.IsUndefined(result) || goja.IsNull(result) {
+		return nil, nil
+	}
+	return result.Export(), nil
+}
+
+// Classify an exception thrown with a status like an HTTP response, anything else is returned as it is
+func (p *jsProvider) classify(err error) error {
+	var exception *goja.Exception
+	if !errors.As(err, &exception) {
+		return fmt.Errorf("%s: %w", p.name, err)
+	}
+
+	thrown, ok := exception.Value().Export().(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: %s", p.name, exception.Error())
+	}
+
+	status, err := strconv.Atoi(toString(thrown["status"]))
+	if err != nil || status < 400 {
+		return fmt.Errorf("%s: %s", p.name, exception.Error())
+	}
+
+	res := &http.Response{StatusCode: status, Status: strconv.Itoa(status) + " " + http.StatusText(status), Header: http.Header{}}
+	if retryAfter := toString(thrown["retryAfter"]); retryAfter != "" {
+		res.Header.Set("Retry-After", retryAfter)
+	}
+	return providers.FromResponse(p.name, res, toString(thrown["message"]))
+}
+
+// Service adapts a registrar defined in JavaScript to types.Service
+type Service struct {
+	provider *jsProvider
+	settings map[string]any
+}
+
+func (s *Service) GetName() string {
+	return s.provider.name
+}
+
+func (s *Service) GetDomains(ctx context.Context) ([]servicetypes.Domain, error) {
+	result, err := s.provider.call(ctx, "getDomains", s.settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []servicetypes.Domain
+	for _, item := range toList(result) {
+		domain, err := toDomain(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.provider.name, err)
+		}
+		domains = append(domains, domain)
+	}
+	return domains, nil
+}
+
+func (s *Service) GetDomainRecords(ctx context.Context, domain string) ([]servicetypes.Record, error) {
+	result, err := s.provider.call(ctx, "getDomainRecords", s.settings, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []servicetypes.Record
+	for _, item := range toList(result) {
+		records = append(records, toRecord(domain, item))
+	}
+	return records, nil
+}
+
+func (s *Service) CreateRecord(ctx context.Context, record servicetypes.Record) (servicetypes.Record, error) {
+	return s.changeRecord(ctx, "createRecord", record)
+}
+
+func (s *Service) UpdateRecord(ctx context.Context, record servicetypes.Record) (servicetypes.Record, error) {
+	return s.changeRecord(ctx, "updateRecord", record)
+}
+
+func (s *Service) DeleteRecord(ctx context.Context, record servicetypes.Record) error {
+	_, err := s.provider.call(ctx, "deleteRecord", s.settings, fromRecord(record))
+	return err
+}
+
+// Create or update a record, the record is returned as it is when the function does not return one
+func (s *Service) changeRecord(ctx context.Context, function string, record servicetypes.Record) (servicetypes.Record, error) {
+	result, err := s.provider.call(ctx, function, s.settings, fromRecord(record))
+	if err != nil {
+		return servicetypes.Record{}, err
+	}
+	if result == nil {
+		return record, nil
+	}
+	return toRecord(record.Domain, result), nil
+}
+
+// Scanner adapts a scanner defined in JavaScript to types.Scanner
+type Scanner struct {
+	provider *jsProvider
+	settings map[string]any
+}
+
+func (s *Scanner) GetName() string {
+	return s.provider.name
+}
+
+// ValidateKey succeeds when the scanner does not define validateKey
+func (s *Scanner) ValidateKey(ctx context.Context) error {
+	if !s.provider.has("validateKey") {
+		return nil
+	}
+	_, err := s.provider.call(ctx, "validateKey", s.settings)
+	return err
+}
+
+func (s *Scanner) GetResults(ctx context.Context, domain string) ([]byte, error) {
+	result, err := s.provider.call(ctx, "getResults", s.settings, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	results, ok := result.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: getResults must return an object", s.provider.name)
+	}
+	if _, ok := results["domain"]; !ok {
+		results["domain"] = domain
+	}
+	return json.Marshal(results)
+}
+
+// GetDailyQuotaRemaining is unlimited when the scanner does not define getDailyQuotaRemaining
+func (s *Scanner) GetDailyQuotaRemaining(ctx context.Context) (int, error) {
+	if !s.provider.has("getDailyQuotaRemaining") {
+		return math.MaxInt32, nil
+	}
+
+	result, err := s.provider.call(ctx, "getDailyQuotaRemaining", s.settings)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining, err := strconv.Atoi(toString(result))
+	if err != nil {
+		return 0, fmt.Errorf("%s: getDailyQuotaRemaining must return a number", s.provider.name)
+	}
+	return remaining, nil
+}
+
+// Convert a domain returned by getDomains
+func toDomain(item any) (servicetypes.Domain, error) {
+	m, _ := item.(map[string]any)
+
+	domain := servicetypes.Domain{
+		Name:       toString(m["name"]),
+		AutoRenew:  toBool(m["autoRenew"]),
+		IsLocked:   toBool(m["isLocked"]),
+		WhoIsGuard: toBool(m["whoisGuard"]),
+		IsOurDNS:   toBool(m["ourDNS"]),
+	}
+	if domain.Name == "" {
+		return domain, errors.New("getDomains returned a domain without a name")
+	}
+
+	var err error
+	if domain.Created, err = toTime(m["created"]); err != nil {
+		return domain, fmt.Errorf("invalid created date for %s: %w", domain.Name, err)
+	}
+	if domain.Expires, err = toTime(m["expires"]); err != nil {
+		return domain, fmt.Errorf("invalid expires date for %s: %w", domain.Name, err)
+	}
+
+	if expired, ok := m["isExpired"].(bool); ok {
+		domain.IsExpired = expired
+	} else {
+		domain.IsExpired = !domain.Expires.IsZero() && domain.Expires.Before(time.Now())
+	}
+	return domain, nil
+}
+
+// Convert a record returned by getDomainRecords, createRecord or updateRecord
+func toRecord(domain string, item any) servicetypes.Record {
+	m, _ := item.(map[string]any)
+
+	return servicetypes.Record{
+		Domain:   domain,
+		HostId:   toString(m["id"]),
+		Name:     toString(m["name"]),
+		Type:     toString(m["type"]),
+		Address:  toString(m["address"]),
+		TTL:      toString(m["ttl"]),
+		Priority: toString(m["priority"]),
+		Proxied:  toBool(m["proxied"]),
+	}
+}
+
+// Convert a record to the object passed to createRecord, updateRecord and deleteRecord
+func fromRecord(record servicetypes.Record) map[string]any {
+	return map[string]any{
+		"domain":   record.Domain,
+		"id":       record.HostId,
+		"name":     record.Name,
+		"type":     record.Type,
+		"address":  record.Address,
+		"ttl":      record.TTL,
+		"priority": record.Priority,
+		"proxied":  record.Proxied,
+	}
+}
+
+// Get a property of an object as a Go value, nil when it is not set
+func exported(object *goja.Object, key string) any {
+	v := object.Get(key)
+	if v == nil {
+		return nil
+	}
+	return v.Export()
+}
+
+func toList(v any) []any {
+	list, _ := v.([]any)
+	return list
+}
+
+func toString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func toBool(v any) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+// Convert a JavaScript Date or a date string
+func toTime(v any) (time.Time, error) {
+	switch x := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return x, nil
+	case string:
+		if x == "" {
+			return time.Time{}, nil
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, x); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unsupported date format %q", x)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported date %v", x)
+	}
+}
@@ -0,0 +1,190 @@
+package jsproviders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/lum8rjack/redcompass/providers"
+)
+
+// Run a hooks script on a new VM with registerProvider bound
+func runScript(t *testing.T, script string) {
+	t.Helper()
+
+	vm := goja.New()
+	Bind(vm)
+	if _, err := vm.RunString(script); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistrar(t *testing.T) {
+	runScript(t, `
+		registerProvider({
+			name: "JS Registrar",
+			kind: "registrar",
+			settings: { type: "object", properties: { apiKey: { type: "string", title: "API Key" } }, required: ["apiKey"] },
+			getDomains: (settings) => {
+				if (settings.apiKey !== "secret") {
+					throw { status: 401, message: "invalid API key" }
+				}
+				return [
+					{ name: "example.com", created: "2024-01-02", expires: new Date("2030-01-02T00:00:00Z"), autoRenew: true },
+					{ name: "expired.com", expires: "2020-01-02 00:00:00" },
+				]
+			},
+			getDomainRecords: (settings, domain) => [{ id: 1, name: "www", type: "A", address: "192.0.2.1", ttl: 300 }],
+			createRecord: (settings, record) => Object.assign({}, record, { id: "2" }),
+		})
+	`)
+
+	p, ok := providers.Lookup("JS Registrar")
+	if !ok || p.Kind != providers.KindRegistrar || p.NewService == nil {
+		t.Fatalf("registrar not registered: %+v", p)
+	}
+	if !strings.Contains(string(p.Settings), `"apiKey"`) {
+		t.Errorf("expected the settings schema, got %s", p.Settings)
+	}
+
+	service, err := p.NewService(`{"apiKey":"secret"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domains, err := service.GetDomains(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 2 {
+		t.Fatalf("expected 2 domains, got %d", len(domains))
+	}
+	if domains[0].Name != "example.com" || !domains[0].AutoRenew || domains[0].IsExpired || domains[0].Expires.Year() != 2030 || domains[0].Created.Year() != 2024 {
+		t.Errorf("unexpected domain %+v", domains[0])
+	}
+	if !domains[1].IsExpired {
+		t.Errorf("expected %s to be expired", domains[1].Name)
+	}
+
+	records, err := service.GetDomainRecords(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].HostId != "1" || records[0].TTL != "300" || records[0].Domain != "example.com" {
+		t.Errorf("unexpected records %+v", records)
+	}
+
+	created, err := service.CreateRecord(context.Background(), records[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.HostId != "2" || created.Address != "192.0.2.1" {
+		t.Errorf("unexpected created record %+v", created)
+	}
+
+	if err := service.DeleteRecord(context.Background(), records[0]); err == nil {
+		t.Error("expected an error for a function the registrar does not define")
+	}
+
+	service, err = p.NewService(`{"apiKey":"wrong"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetDomains(context.Background()); !errors.Is(err, providers.ErrAuth) {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	runScript(t, `
+		registerProvider({
+			name: "JS Scanner",
+			kind: "scanner",
+			getResults: (settings, domain) => {
+				if (domain === "limited.com") {
+					throw { status: 429, retryAfter: 30, message: "slow down" }
+				}
+				return { malicious: 2, harmless: 60 }
+			},
+		})
+	`)
+
+	p, ok := providers.Lookup("JS Scanner")
+	if !ok || p.Kind != providers.KindScanner || p.NewScanner == nil {
+		t.Fatalf("scanner not registered: %+v", p)
+	}
+
+	scanner, err := p.NewScanner(`{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scanner.ValidateKey(context.Background()); err != nil {
+		t.Errorf("expected validateKey to be optional, got %v", err)
+	}
+	if remaining, err := scanner.GetDailyQuotaRemaining(context.Background()); err != nil || remaining <= 0 {
+		t.Errorf("expected an unlimited quota, got %d %v", remaining, err)
+	}
+
+	results, err := scanner.GetResults(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stats struct {
+		Domain    string `json:"domain"`
+		Malicious int    `json:"malicious"`
+		Harmless  int    `json:"harmless"`
+	}
+	if err := json.Unmarshal(results, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Domain != "example.com" || stats.Malicious != 2 || stats.Harmless != 60 {
+		t.Errorf("unexpected results %s", results)
+	}
+
+	_, err = scanner.GetResults(context.Background(), "limited.com")
+	if !errors.Is(err, providers.ErrRateLimited) || providers.RetryAfter(err) != 30*time.Second {
+		t.Errorf("expected a rate limit with a 30s Retry-After, got %v", err)
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	runScript(t, `
+		registerProvider({
+			name: "JS Slow",
+			kind: "registrar",
+			getDomains: () => { while (true) {} },
+			getDomainRecords: () => [],
+		})
+	`)
+
+	p, _ := providers.Lookup("JS Slow")
+	service, err := p.NewService(`{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := service.GetDomains(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the call to be interrupted, got %v", err)
+	}
+}
+
+func TestInvalidDefinition(t *testing.T) {
+	vm := goja.New()
+	Bind(vm)
+
+	for _, script := range []string{
+		`registerProvider()`,
+		`registerProvider({ name: "JS Invalid", kind: "registrar", getDomains: () => [] })`,
+		`registerProvider({ name: "JS Invalid", kind: "other", getResults: () => ({}) })`,
+	} {
+		if _, err := vm.RunString(script); err == nil {
+			t.Errorf("expected an error for %s", script)
+		}
+	}
+}
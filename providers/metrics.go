@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request metrics for a provider
+type Metrics struct {
+	Provider        string         `json:"provider"`
+	Requests        int            `json:"requests"`
+	Errors          int            `json:"errors"`
+	RateLimited     int            `json:"rate_limited"`
+	Retries         int            `json:"retries"`
+	AverageDuration float64        `json:"average_duration_ms"`
+	LastRequest     time.Time      `json:"last_request"`
+	StatusCodes     map[string]int `json:"status_codes"`
+	totalDuration   time.Duration
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   = map[string]*Metrics{}
+)
+
+// Get the metrics for a provider, the lock must be held
+func providerMetrics(provider string) *Metrics {
+	m, ok := metrics[provider]
+	if !ok {
+		m = &Metrics{Provider: provider, StatusCodes: map[string]int{}}
+		metrics[provider] = m
+	}
+	return m
+}
+
+// Record a single request sent to a provider
+func recordRequest(provider string, resp *http.Response, err error, duration time.Duration) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	m := providerMetrics(provider)
+	m.Requests++
+	m.totalDuration += duration
+	m.LastRequest = time.Now().UTC()
+
+	if err != nil {
+		m.Errors++
+		return
+	}
+
+	m.StatusCodes[strconv.Itoa(resp.StatusCode)]++
+	if resp.StatusCode == http.StatusTooManyRequests {
+		m.RateLimited++
+	}
+	if resp.StatusCode >= 400 {
+		m.Errors++
+	}
+}
+
+// Record a retried request
+func recordRetry(provider string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	providerMetrics(provider).Retries++
+}
+
+// GetMetrics returns a copy of the request metrics for each provider since the app started
+func GetMetrics() []Metrics {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	result := make([]Metrics, 0, len(metrics))
+	for _, provider := range slices.Sorted(maps.Keys(metrics)) {
+		m := *metrics[provider]
+		m.StatusCodes = maps.Clone(m.StatusCodes)
+		if m.Requests > 0 {
+			m.AverageDuration = float64(m.totalDuration) / float64(m.Requests) / float64(time.Millisecond)
+		}
+		result = append(result, m)
+	}
+
+	return result
+}
@@ -0,0 +1,32 @@
+package providertest
+
+import "sync"
+
+// Categories holds the categories served by the fake categorisation checker APIs
+type Categories struct {
+	faults
+
+	mu         sync.Mutex
+	categories map[string]string
+}
+
+// NewCategories creates a checker where every domain is uncategorised
+func NewCategories() *Categories {
+	return &Categories{categories: map[string]string{}}
+}
+
+// SetCategory sets the category returned for a domain
+func (c *Categories) SetCategory(domain string, category string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.categories[domain] = category
+}
+
+// Look up the category of a domain, empty when it is uncategorised
+func (c *Categories) lookup(domain string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.categories[domain]
+}
@@ -0,0 +1,236 @@
+package providertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/services/types"
+)
+
+// Account returned by the fake Cloudflare API
+const cloudflareAccountID = "account-1"
+
+// Body of the requests to create or update a DNS record
+type cloudflareRecordRequest struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority"`
+	Proxied  bool   `json:"proxied"`
+}
+
+// CloudflareHandler serves the Cloudflare v4 API for the registrar, the base URL of the client
+// is the URL of the server. Every domain is registered with Cloudflare Registrar and has a zone.
+func CloudflareHandler(r *Registrar) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /accounts", func(w http.ResponseWriter, req *http.Request) {
+		accounts := []map[string]any{{"id": cloudflareAccountID, "name": "Test Account"}}
+		cloudflarePage(w, req, accounts)
+	})
+
+	mux.HandleFunc("GET /accounts/{account}/registrar/domains", func(w http.ResponseWriter, req *http.Request) {
+		if req.PathValue("account") != cloudflareAccountID {
+			cloudflareError(w, http.StatusForbidden, 9109, "Unauthorized to access requested resource")
+			return
+		}
+
+		domains := []map[string]any{}
+		for _, d := range r.domainList() {
+			domains = append(domains, map[string]any{
+				"id":         "registrar-" + d.Name,
+				"name":       d.Name,
+				"auto_renew": d.AutoRenew,
+				"locked":     d.IsLocked,
+				"privacy":    d.WhoIsGuard,
+				"created_at": d.Created.Format(time.RFC3339),
+				"expires_at": d.Expires.Format(time.RFC3339),
+			})
+		}
+		cloudflarePage(w, req, domains)
+	})
+
+	mux.HandleFunc("GET /zones", func(w http.ResponseWriter, req *http.Request) {
+		name := req.URL.Query().Get("name")
+
+		zones := []map[string]any{}
+		for _, d := range r.domainList() {
+			if name != "" && d.Name != name {
+				continue
+			}
+
+			ns := []string{"ns1.example.net", "ns2.example.net"}
+			if d.IsOurDNS {
+				ns = []string{"ada.ns.cloudflare.com", "bob.ns.cloudflare.com"}
+			}
+			zones = append(zones, map[string]any{
+				"id":           "zone-" + d.Name,
+				"name":         d.Name,
+				"status":       "active",
+				"paused":       false,
+				"name_servers": ns,
+			})
+		}
+		cloudflarePage(w, req, zones)
+	})
+
+	mux.HandleFunc("GET /zones/{zone}/dns_records", func(w http.ResponseWriter, req *http.Request) {
+		domain := strings.TrimPrefix(req.PathValue("zone"), "zone-")
+		records, ok := r.domainRecords(domain)
+		if !ok {
+			cloudflareError(w, http.StatusNotFound, 7003, "Could not route to /zones, perhaps your object identifier is invalid?")
+			return
+		}
+
+		result := []map[string]any{}
+		for _, x := range records {
+			result = append(result, cloudflareRecord(x))
+		}
+		cloudflarePage(w, req, result)
+	})
+
+	mux.HandleFunc("POST /zones/{zone}/dns_records", func(w http.ResponseWriter, req *http.Request) {
+		domain := strings.TrimPrefix(req.PathValue("zone"), "zone-")
+		record, ok := cloudflareBody(w, req)
+		if !ok {
+			return
+		}
+
+		record, ok = r.createRecord(domain, record)
+		if !ok {
+			cloudflareError(w, http.StatusNotFound, 7003, "Could not route to /zones, perhaps your object identifier is invalid?")
+			return
+		}
+		cloudflareSuccess(w, cloudflareRecord(record), nil)
+	})
+
+	mux.HandleFunc("PUT /zones/{zone}/dns_records/{id}", func(w http.ResponseWriter, req *http.Request) {
+		domain := strings.TrimPrefix(req.PathValue("zone"), "zone-")
+		record, ok := cloudflareBody(w, req)
+		if !ok {
+			return
+		}
+
+		record, ok = r.updateRecord(domain, req.PathValue("id"), record)
+		if !ok {
+			cloudflareError(w, http.StatusNotFound, 81044, "Record does not exist.")
+			return
+		}
+		cloudflareSuccess(w, cloudflareRecord(record), nil)
+	})
+
+	mux.HandleFunc("DELETE /zones/{zone}/dns_records/{id}", func(w http.ResponseWriter, req *http.Request) {
+		domain := strings.TrimPrefix(req.PathValue("zone"), "zone-")
+		if !r.deleteRecord(domain, req.PathValue("id")) {
+			cloudflareError(w, http.StatusNotFound, 81044, "Record does not exist.")
+			return
+		}
+		cloudflareSuccess(w, map[string]any{"id": req.PathValue("id")}, nil)
+	})
+
+	// Every request needs the API token
+	authenticated := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer ") {
+			cloudflareError(w, http.StatusUnauthorized, 10000, "Authentication error")
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+
+	return r.wrap(authenticated, func(w http.ResponseWriter, failure Failure) {
+		if failure == FailAuth {
+			cloudflareError(w, http.StatusForbidden, 10000, "Authentication error")
+			return
+		}
+		cloudflareError(w, http.StatusTooManyRequests, 971, "Please wait and consider throttling your request speed")
+	})
+}
+
+// Decode the body of a create or update request
+func cloudflareBody(w http.ResponseWriter, req *http.Request) (types.Record, bool) {
+	var body cloudflareRecordRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		cloudflareError(w, http.StatusBadRequest, 9207, "Request body is invalid.")
+		return types.Record{}, false
+	}
+
+	record := types.Record{
+		Name:    body.Name,
+		Type:    body.Type,
+		Address: body.Content,
+		TTL:     strconv.Itoa(body.TTL),
+		Proxied: body.Proxied,
+	}
+	if body.TTL == 1 {
+		record.TTL = "auto"
+	}
+	if body.Priority != nil {
+		record.Priority = strconv.Itoa(*body.Priority)
+	}
+
+	return record, true
+}
+
+// Convert a record to the format returned by the API
+func cloudflareRecord(record types.Record) map[string]any {
+	ttl := 1
+	if record.TTL != "auto" {
+		ttl, _ = strconv.Atoi(record.TTL)
+	}
+
+	result := map[string]any{
+		"id":      record.HostId,
+		"name":    fullName(record.Name, record.Domain),
+		"type":    record.Type,
+		"content": record.Address,
+		"ttl":     ttl,
+		"proxied": record.Proxied,
+	}
+	if priority, err := strconv.Atoi(record.Priority); err == nil {
+		result["priority"] = priority
+	}
+
+	return result
+}
+
+// Write a page of a list using the page and per_page query parameters
+func cloudflarePage[T any](w http.ResponseWriter, req *http.Request, items []T) {
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	perPage, _ := strconv.Atoi(req.URL.Query().Get("per_page"))
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = 20
+	}
+
+	result, totalPages := paginate(items, page, perPage)
+	cloudflareSuccess(w, result, map[string]any{
+		"page":        page,
+		"per_page":    perPage,
+		"count":       len(result),
+		"total_count": len(items),
+		"total_pages": totalPages,
+	})
+}
+
+func cloudflareSuccess(w http.ResponseWriter, result any, resultInfo map[string]any) {
+	response := map[string]any{"success": true, "errors": []any{}, "messages": []any{}, "result": result}
+	if resultInfo != nil {
+		response["result_info"] = resultInfo
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func cloudflareError(w http.ResponseWriter, status int, code int, message string) {
+	writeJSON(w, status, map[string]any{
+		"success":  false,
+		"errors":   []map[string]any{{"code": code, "message": message}},
+		"messages": []any{},
+		"result":   nil,
+	})
+}
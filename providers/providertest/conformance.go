@@ -0,0 +1,515 @@
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lum8rjack/redcompass/providers"
+	scannertypes "github.com/lum8rjack/redcompass/scanners/types"
+	"github.com/lum8rjack/redcompass/services/types"
+)
+
+// ServiceSuite describes a types.Service implementation to run the conformance tests against
+type ServiceSuite struct {
+	// Create the service using the URL of the fake API as the base URL. Rate limiters should
+	// be disabled so the tests run quickly.
+	New func(t *testing.T, baseURL string) types.Service
+
+	// Fake API of the provider
+	Handler func(*Registrar) http.Handler
+
+	// Number of domains returned per page by the provider
+	PageSize int
+}
+
+// ScannerSuite describes a types.Scanner implementation to run the conformance tests against
+type ScannerSuite struct {
+	// Create the scanner using the URL of the fake API as the base URL. Rate limiters should
+	// be disabled so the tests run quickly.
+	New func(t *testing.T, baseURL string) scannertypes.Scanner
+
+	// Fake API of the provider
+	Handler func(*Scans) http.Handler
+}
+
+// CheckerSuite describes a types.Checker implementation to run the conformance tests against
+type CheckerSuite struct {
+	// Create the checker using the URL of the fake API as the base URL. Rate limiters should
+	// be disabled so the tests run quickly.
+	New func(t *testing.T, baseURL string) scannertypes.Checker
+
+	// Fake API of the vendor
+	Handler func(*Categories) http.Handler
+}
+
+// Start a fake registrar and create the service for it
+func (s ServiceSuite) start(t *testing.T) (*Registrar, types.Service) {
+	registrar := NewRegistrar()
+	server := httptest.NewServer(s.Handler(registrar))
+	t.Cleanup(server.Close)
+
+	return registrar, s.New(t, server.URL)
+}
+
+// Start a fake scanner and create the scanner for it
+func (s ScannerSuite) start(t *testing.T) (*Scans, scannertypes.Scanner) {
+	scans := NewScans()
+	server := httptest.NewServer(s.Handler(scans))
+	t.Cleanup(server.Close)
+
+	return scans, s.New(t, server.URL)
+}
+
+// Start a fake checker and create the checker for it
+func (s CheckerSuite) start(t *testing.T) (*Categories, scannertypes.Checker) {
+	categories := NewCategories()
+	server := httptest.NewServer(s.Handler(categories))
+	t.Cleanup(server.Close)
+
+	return categories, s.New(t, server.URL)
+}
+
+// Create a domain that expires in a year, or expired a month ago
+func testDomain(name string, expired bool) types.Domain {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	expires := today.AddDate(1, 0, 0)
+	if expired {
+		expires = today.AddDate(0, -1, 0)
+	}
+
+	return types.Domain{
+		Name:       name,
+		Created:    today.AddDate(-1, 0, 0),
+		Expires:    expires,
+		IsExpired:  expired,
+		AutoRenew:  true,
+		WhoIsGuard: true,
+		IsOurDNS:   true,
+	}
+}
+
+// Find a record by type and address since each provider returns the names differently
+func findRecord(records []types.Record, recordType string, address string) (types.Record, bool) {
+	for _, r := range records {
+		if r.Type == recordType && r.Address == address {
+			return r, true
+		}
+	}
+	return types.Record{}, false
+}
+
+// Check the error is of the given kind
+func requireKind(t *testing.T, err error, kind error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected a %q error, got nil", kind)
+	}
+	if !errors.Is(err, kind) {
+		t.Fatalf("expected a %q error, got %v", kind, err)
+	}
+}
+
+// RunServiceTests runs the conformance tests for a registrar service
+func RunServiceTests(t *testing.T, suite ServiceSuite) {
+	ctx := context.Background()
+
+	t.Run("EmptyAccount", func(t *testing.T) {
+		t.Parallel()
+		_, service := suite.start(t)
+
+		domains, err := service.GetDomains(ctx)
+		if err != nil {
+			t.Fatalf("GetDomains: %v", err)
+		}
+		if len(domains) != 0 {
+			t.Fatalf("expected no domains, got %d", len(domains))
+		}
+	})
+
+	t.Run("Domains", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		active := testDomain("active-domain.com", false)
+		expired := testDomain("expired-domain.com", true)
+		registrar.AddDomain(active)
+		registrar.AddDomain(expired)
+
+		domains, err := service.GetDomains(ctx)
+		if err != nil {
+			t.Fatalf("GetDomains: %v", err)
+		}
+		if len(domains) != 2 {
+			t.Fatalf("expected 2 domains, got %d", len(domains))
+		}
+
+		for _, want := range []types.Domain{active, expired} {
+			var got *types.Domain
+			for i := range domains {
+				if domains[i].Name == want.Name {
+					got = &domains[i]
+				}
+			}
+			if got == nil {
+				t.Fatalf("domain %s was not returned", want.Name)
+			}
+			if got.Expires.Format(time.DateOnly) != want.Expires.Format(time.DateOnly) {
+				t.Errorf("%s: expected expiry %s, got %s", want.Name, want.Expires.Format(time.DateOnly), got.Expires.Format(time.DateOnly))
+			}
+			if got.IsExpired != want.IsExpired {
+				t.Errorf("%s: expected IsExpired %t, got %t", want.Name, want.IsExpired, got.IsExpired)
+			}
+			if !got.IsOurDNS {
+				t.Errorf("%s: expected the domain to use the provider DNS", want.Name)
+			}
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		total := suite.PageSize*2 + 1
+		for i := range total {
+			registrar.AddDomain(testDomain(fmt.Sprintf("domain-%05d.com", i), false))
+		}
+
+		domains, err := service.GetDomains(ctx)
+		if err != nil {
+			t.Fatalf("GetDomains: %v", err)
+		}
+		if len(domains) != total {
+			t.Fatalf("expected %d domains, got %d", total, len(domains))
+		}
+
+		seen := map[string]bool{}
+		for _, d := range domains {
+			if seen[d.Name] {
+				t.Fatalf("domain %s was returned more than once", d.Name)
+			}
+			seen[d.Name] = true
+		}
+
+		if totaler, ok := service.(types.DomainTotaler); ok && totaler.GetTotalDomains() != total {
+			t.Fatalf("expected a total of %d domains, got %d", total, totaler.GetTotalDomains())
+		}
+	})
+
+	t.Run("Records", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.AddDomain(testDomain("records.com", false),
+			types.Record{Name: "@", Type: "A", Address: "192.0.2.1", TTL: "600"},
+			types.Record{Name: "www", Type: "CNAME", Address: "records.com", TTL: "600"},
+			types.Record{Name: "@", Type: "MX", Address: "mail.records.com", TTL: "600", Priority: "20"},
+		)
+
+		records, err := service.GetDomainRecords(ctx, "records.com")
+		if err != nil {
+			t.Fatalf("GetDomainRecords: %v", err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("expected 3 records, got %d", len(records))
+		}
+
+		for _, r := range records {
+			if r.HostId == "" {
+				t.Errorf("record %s %s has no host id", r.Type, r.Address)
+			}
+			if r.Domain != "records.com" {
+				t.Errorf("record %s %s has domain %q", r.Type, r.Address, r.Domain)
+			}
+		}
+
+		mx, ok := findRecord(records, "MX", "mail.records.com")
+		if !ok {
+			t.Fatal("MX record was not returned")
+		}
+		if mx.Priority != "20" {
+			t.Fatalf("expected MX priority 20, got %q", mx.Priority)
+		}
+	})
+
+	t.Run("NoRecords", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.AddDomain(testDomain("empty.com", false))
+
+		records, err := service.GetDomainRecords(ctx, "empty.com")
+		if err != nil {
+			t.Fatalf("GetDomainRecords: %v", err)
+		}
+		if len(records) != 0 {
+			t.Fatalf("expected no records, got %d", len(records))
+		}
+	})
+
+	t.Run("UnknownDomain", func(t *testing.T) {
+		t.Parallel()
+		_, service := suite.start(t)
+
+		_, err := service.GetDomainRecords(ctx, "missing.com")
+		requireKind(t, err, providers.ErrNotFound)
+	})
+
+	t.Run("RecordLifecycle", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.AddDomain(testDomain("lifecycle.com", false),
+			types.Record{Name: "@", Type: "A", Address: "192.0.2.1", TTL: "600"},
+		)
+
+		created, err := service.CreateRecord(ctx, types.Record{Domain: "lifecycle.com", Name: "www", Type: "A", Address: "192.0.2.10", TTL: "600"})
+		if err != nil {
+			t.Fatalf("CreateRecord: %v", err)
+		}
+		if created.HostId == "" {
+			t.Fatal("created record has no host id")
+		}
+		if _, ok := findRecord(registrar.Records("lifecycle.com"), "A", "192.0.2.10"); !ok {
+			t.Fatal("created record is not on the registrar")
+		}
+
+		created.Address = "192.0.2.20"
+		updated, err := service.UpdateRecord(ctx, created)
+		if err != nil {
+			t.Fatalf("UpdateRecord: %v", err)
+		}
+		records := registrar.Records("lifecycle.com")
+		if _, ok := findRecord(records, "A", "192.0.2.20"); !ok {
+			t.Fatal("updated record is not on the registrar")
+		}
+		if _, ok := findRecord(records, "A", "192.0.2.10"); ok {
+			t.Fatal("old record is still on the registrar")
+		}
+
+		if err := service.DeleteRecord(ctx, updated); err != nil {
+			t.Fatalf("DeleteRecord: %v", err)
+		}
+		records = registrar.Records("lifecycle.com")
+		if _, ok := findRecord(records, "A", "192.0.2.20"); ok {
+			t.Fatal("deleted record is still on the registrar")
+		}
+		if _, ok := findRecord(records, "A", "192.0.2.1"); !ok {
+			t.Fatal("other records were removed")
+		}
+	})
+
+	t.Run("AuthError", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.Fail(FailAuth, -1)
+
+		_, err := service.GetDomains(ctx)
+		requireKind(t, err, providers.ErrAuth)
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.Fail(FailRateLimit, -1)
+
+		_, err := service.GetDomains(ctx)
+		requireKind(t, err, providers.ErrRateLimited)
+		if providers.RetryAfter(err) != RateLimitRetryAfter*time.Second {
+			t.Fatalf("expected to retry after %ds, got %s", RateLimitRetryAfter, providers.RetryAfter(err))
+		}
+	})
+
+	t.Run("TransientErrorRetried", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.AddDomain(testDomain("retried.com", false))
+		registrar.Fail(FailUnavailable, 1)
+
+		domains, err := service.GetDomains(ctx)
+		if err != nil {
+			t.Fatalf("GetDomains: %v", err)
+		}
+		if len(domains) != 1 {
+			t.Fatalf("expected 1 domain, got %d", len(domains))
+		}
+	})
+
+	t.Run("TransientError", func(t *testing.T) {
+		t.Parallel()
+		registrar, service := suite.start(t)
+		registrar.Fail(FailUnavailable, -1)
+
+		_, err := service.GetDomains(ctx)
+		requireKind(t, err, providers.ErrTransient)
+	})
+}
+
+// RunScannerTests runs the conformance tests for a scanner
+func RunScannerTests(t *testing.T, suite ScannerSuite) {
+	ctx := context.Background()
+
+	t.Run("ValidateKey", func(t *testing.T) {
+		t.Parallel()
+		_, scanner := suite.start(t)
+
+		if err := scanner.ValidateKey(ctx); err != nil {
+			t.Fatalf("ValidateKey: %v", err)
+		}
+	})
+
+	t.Run("Results", func(t *testing.T) {
+		t.Parallel()
+		scans, scanner := suite.start(t)
+		scans.SetResult("flagged.com", ScanResult{Malicious: 3, Suspicious: 1, Harmless: 50, Undetected: 10})
+
+		results, err := scanner.GetResults(ctx, "flagged.com")
+		if err != nil {
+			t.Fatalf("GetResults: %v", err)
+		}
+		if !json.Valid(results) {
+			t.Fatalf("results are not valid JSON: %s", results)
+		}
+	})
+
+	t.Run("EmptyResults", func(t *testing.T) {
+		t.Parallel()
+		scans, scanner := suite.start(t)
+		scans.SetResult("new.com", ScanResult{})
+
+		results, err := scanner.GetResults(ctx, "new.com")
+		if err != nil {
+			t.Fatalf("GetResults: %v", err)
+		}
+		if !json.Valid(results) {
+			t.Fatalf("results are not valid JSON: %s", results)
+		}
+	})
+
+	t.Run("UnknownDomain", func(t *testing.T) {
+		t.Parallel()
+		_, scanner := suite.start(t)
+
+		_, err := scanner.GetResults(ctx, "missing.com")
+		requireKind(t, err, providers.ErrNotFound)
+	})
+
+	t.Run("Quota", func(t *testing.T) {
+		t.Parallel()
+		scans, scanner := suite.start(t)
+		scans.SetResult("quota.com", ScanResult{Harmless: 60})
+		scans.SetUsed(10)
+
+		before, err := scanner.GetDailyQuotaRemaining(ctx)
+		if err != nil {
+			t.Fatalf("GetDailyQuotaRemaining: %v", err)
+		}
+		if _, err := scanner.GetResults(ctx, "quota.com"); err != nil {
+			t.Fatalf("GetResults: %v", err)
+		}
+		after, err := scanner.GetDailyQuotaRemaining(ctx)
+		if err != nil {
+			t.Fatalf("GetDailyQuotaRemaining: %v", err)
+		}
+		if after != before-1 {
+			t.Fatalf("expected the quota to go from %d to %d, got %d", before, before-1, after)
+		}
+	})
+
+	t.Run("AuthError", func(t *testing.T) {
+		t.Parallel()
+		scans, scanner := suite.start(t)
+		scans.Fail(FailAuth, -1)
+
+		requireKind(t, scanner.ValidateKey(ctx), providers.ErrAuth)
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		t.Parallel()
+		scans, scanner := suite.start(t)
+		scans.SetResult("limited.com", ScanResult{})
+		scans.Fail(FailRateLimit, -1)
+
+		_, err := scanner.GetResults(ctx, "limited.com")
+		requireKind(t, err, providers.ErrRateLimited)
+		if providers.RetryAfter(err) != RateLimitRetryAfter*time.Second {
+			t.Fatalf("expected to retry after %ds, got %s", RateLimitRetryAfter, providers.RetryAfter(err))
+		}
+	})
+
+	t.Run("TransientErrorRetried", func(t *testing.T) {
+		t.Parallel()
+		scans, scanner := suite.start(t)
+		scans.SetResult("retried.com", ScanResult{Harmless: 60})
+		scans.Fail(FailUnavailable, 1)
+
+		if _, err := scanner.GetResults(ctx, "retried.com"); err != nil {
+			t.Fatalf("GetResults: %v", err)
+		}
+	})
+}
+
+// RunCheckerTests runs the conformance tests for a categorisation checker
+func RunCheckerTests(t *testing.T, suite CheckerSuite) {
+	ctx := context.Background()
+
+	t.Run("Category", func(t *testing.T) {
+		t.Parallel()
+		categories, checker := suite.start(t)
+		categories.SetCategory("categorized.com", "business-and-economy")
+
+		category, err := checker.GetCategory(ctx, "categorized.com")
+		if err != nil {
+			t.Fatalf("GetCategory: %v", err)
+		}
+		if !strings.HasPrefix(category, "business-and-economy") {
+			t.Fatalf("expected the category business-and-economy, got %q", category)
+		}
+	})
+
+	t.Run("Uncategorized", func(t *testing.T) {
+		t.Parallel()
+		_, checker := suite.start(t)
+
+		category, err := checker.GetCategory(ctx, "new.com")
+		if err != nil {
+			t.Fatalf("GetCategory: %v", err)
+		}
+		if category != scannertypes.Uncategorized {
+			t.Fatalf("expected %q, got %q", scannertypes.Uncategorized, category)
+		}
+	})
+
+	t.Run("AuthError", func(t *testing.T) {
+		t.Parallel()
+		categories, checker := suite.start(t)
+		categories.Fail(FailAuth, -1)
+
+		_, err := checker.GetCategory(ctx, "denied.com")
+		requireKind(t, err, providers.ErrAuth)
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		t.Parallel()
+		categories, checker := suite.start(t)
+		categories.Fail(FailRateLimit, -1)
+
+		_, err := checker.GetCategory(ctx, "limited.com")
+		requireKind(t, err, providers.ErrRateLimited)
+		if providers.RetryAfter(err) != RateLimitRetryAfter*time.Second {
+			t.Fatalf("expected to retry after %ds, got %s", RateLimitRetryAfter, providers.RetryAfter(err))
+		}
+	})
+
+	t.Run("TransientErrorRetried", func(t *testing.T) {
+		t.Parallel()
+		categories, checker := suite.start(t)
+		categories.SetCategory("retried.com", "computers-and-internet")
+		categories.Fail(FailUnavailable, 1)
+
+		if _, err := checker.GetCategory(ctx, "retried.com"); err != nil {
+			t.Fatalf("GetCategory: %v", err)
+		}
+	})
+}
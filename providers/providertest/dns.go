@@ -0,0 +1,177 @@
+package providertest
+
+import (
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNS holds the A, MX and TXT records served by the fake DNS server, blocklist entries are A
+// records under the zone of the list
+type DNS struct {
+	faults
+
+	mu  sync.Mutex
+	a   map[string][]string
+	mx  map[string][]string
+	txt map[string][]string
+}
+
+// NewDNS creates a DNS server without any records, every name is NXDOMAIN
+func NewDNS() *DNS {
+	return &DNS{a: map[string][]string{}, mx: map[string][]string{}, txt: map[string][]string{}}
+}
+
+// SetA sets the IPv4 addresses of a name
+func (d *DNS) SetA(name string, addresses ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.a[dnsKey(name)] = addresses
+}
+
+// SetMX sets the mail servers of a domain
+func (d *DNS) SetMX(domain string, hosts ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.mx[dnsKey(domain)] = hosts
+}
+
+// SetTXT sets the TXT records of a name, e.g. the SPF record of a domain
+func (d *DNS) SetTXT(name string, records ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.txt[dnsKey(name)] = records
+}
+
+// ListDomain lists a domain on a domain blocklist (RHSBL) with the return codes
+func (d *DNS) ListDomain(zone string, domain string, codes ...string) {
+	d.SetA(domain+"."+zone, codes...)
+}
+
+// ListIP lists an IPv4 address on an IP blocklist (DNSBL) with the return codes
+func (d *DNS) ListIP(zone string, address string, codes ...string) {
+	octets := strings.Split(address, ".")
+	slices.Reverse(octets)
+	d.SetA(strings.Join(octets, ".")+"."+zone, codes...)
+}
+
+func (d *DNS) records(name string) ([]string, []string, []string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	a, hasA := d.a[dnsKey(name)]
+	mx, hasMX := d.mx[dnsKey(name)]
+	txt, hasTXT := d.txt[dnsKey(name)]
+	return a, mx, txt, hasA || hasMX || hasTXT
+}
+
+func dnsKey(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// StartDNS starts a fake DNS server over UDP and returns its address to use as the resolver.
+// Injected failures are answered with REFUSED for FailAuth and SERVFAIL otherwise.
+func StartDNS(t *testing.T, d *DNS) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if response, ok := d.answer(buf[:n]); ok {
+				_, _ = conn.WriteTo(response, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// Build the response to a query
+func (d *DNS) answer(query []byte) ([]byte, bool) {
+	var request dnsmessage.Message
+	if err := request.Unpack(query); err != nil || len(request.Questions) != 1 {
+		return nil, false
+	}
+
+	question := request.Questions[0]
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 request.ID,
+			Response:           true,
+			Authoritative:      true,
+			RecursionDesired:   request.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: request.Questions,
+	}
+
+	switch d.next() {
+	case FailAuth:
+		response.RCode = dnsmessage.RCodeRefused
+		return pack(response)
+	case FailRateLimit, FailUnavailable:
+		response.RCode = dnsmessage.RCodeServerFailure
+		return pack(response)
+	}
+
+	a, mx, txt, ok := d.records(question.Name.String())
+	if !ok {
+		response.RCode = dnsmessage.RCodeNameError
+		return pack(response)
+	}
+
+	header := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 60}
+	switch question.Type {
+	case dnsmessage.TypeA:
+		for _, address := range a {
+			ip, err := netip.ParseAddr(address)
+			if err != nil || !ip.Is4() {
+				continue
+			}
+			response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: ip.As4()}})
+		}
+	case dnsmessage.TypeMX:
+		for i, host := range mx {
+			response.Answers = append(response.Answers, dnsmessage.Resource{
+				Header: header,
+				Body:   &dnsmessage.MXResource{Pref: uint16(10 * (i + 1)), MX: dnsmessage.MustNewName(dnsKey(host) + ".")},
+			})
+		}
+	case dnsmessage.TypeTXT:
+		for _, record := range txt {
+			response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.TXTResource{TXT: txtStrings(record)}})
+		}
+	}
+	return pack(response)
+}
+
+// Split a TXT record into the strings of at most 255 characters of the wire format
+func txtStrings(record string) []string {
+	var parts []string
+	for len(record) > 255 {
+		parts = append(parts, record[:255])
+		record = record[255:]
+	}
+	return append(parts, record)
+}
+
+func pack(message dnsmessage.Message) ([]byte, bool) {
+	b, err := message.Pack()
+	return b, err == nil
+}
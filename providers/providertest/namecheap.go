@@ -0,0 +1,210 @@
+package providertest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lum8rjack/redcompass/services/types"
+)
+
+type namecheapResponse struct {
+	XMLName         xml.Name                  `xml:"ApiResponse"`
+	Status          string                    `xml:"Status,attr"`
+	Errors          []namecheapError          `xml:"Errors>Error"`
+	CommandResponse *namecheapCommandResponse `xml:"CommandResponse,omitempty"`
+}
+
+type namecheapError struct {
+	Number  string `xml:"Number,attr"`
+	Message string `xml:",chardata"`
+}
+
+type namecheapCommandResponse struct {
+	Type     string                   `xml:"Type,attr"`
+	Domains  []namecheapDomain        `xml:"DomainGetListResult>Domain"`
+	Paging   *namecheapPaging         `xml:"Paging,omitempty"`
+	Hosts    *namecheapHostsResult    `xml:"DomainDNSGetHostsResult,omitempty"`
+	SetHosts *namecheapSetHostsResult `xml:"DomainDNSSetHostsResult,omitempty"`
+}
+
+type namecheapDomain struct {
+	ID         string `xml:"ID,attr"`
+	Name       string `xml:"Name,attr"`
+	User       string `xml:"User,attr"`
+	Created    string `xml:"Created,attr"`
+	Expires    string `xml:"Expires,attr"`
+	IsExpired  bool   `xml:"IsExpired,attr"`
+	IsLocked   bool   `xml:"IsLocked,attr"`
+	AutoRenew  bool   `xml:"AutoRenew,attr"`
+	WhoisGuard string `xml:"WhoisGuard,attr"`
+	IsPremium  bool   `xml:"IsPremium,attr"`
+	IsOurDNS   bool   `xml:"IsOurDNS,attr"`
+}
+
+type namecheapPaging struct {
+	TotalItems  int `xml:"TotalItems"`
+	CurrentPage int `xml:"CurrentPage"`
+	PageSize    int `xml:"PageSize"`
+}
+
+type namecheapHostsResult struct {
+	Domain        string          `xml:"Domain,attr"`
+	EmailType     string          `xml:"EmailType,attr"`
+	IsUsingOurDNS bool            `xml:"IsUsingOurDNS,attr"`
+	Hosts         []namecheapHost `xml:"host"`
+}
+
+type namecheapHost struct {
+	HostId  string `xml:"HostId,attr"`
+	Name    string `xml:"Name,attr"`
+	Type    string `xml:"Type,attr"`
+	Address string `xml:"Address,attr"`
+	MXPref  string `xml:"MXPref,attr"`
+	TTL     string `xml:"TTL,attr"`
+}
+
+type namecheapSetHostsResult struct {
+	Domain    string `xml:"Domain,attr"`
+	IsSuccess bool   `xml:"IsSuccess,attr"`
+}
+
+// NamecheapHandler serves the Namecheap XML API for the registrar, the base URL of the client
+// is the URL of the server. The commands used by the service are supported.
+func NamecheapHandler(r *Registrar) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			namecheapFail(w, "1010101", "Invalid request")
+			return
+		}
+
+		if req.Form.Get("ApiKey") == "" || req.Form.Get("ApiUser") == "" || req.Form.Get("ClientIp") == "" {
+			namecheapFail(w, "1011102", "Parameter APIKey is missing")
+			return
+		}
+
+		domain := req.Form.Get("SLD") + "." + req.Form.Get("TLD")
+		command := req.Form.Get("Command")
+		switch command {
+		case "namecheap.domains.getList":
+			page, _ := strconv.Atoi(req.Form.Get("Page"))
+			pageSize, _ := strconv.Atoi(req.Form.Get("PageSize"))
+			if page < 1 {
+				page = 1
+			}
+			if pageSize < 1 {
+				pageSize = 20
+			}
+
+			domains := r.domainList()
+			result, _ := paginate(domains, page, pageSize)
+
+			response := &namecheapCommandResponse{
+				Type:   command,
+				Paging: &namecheapPaging{TotalItems: len(domains), CurrentPage: page, PageSize: pageSize},
+			}
+			for i, d := range result {
+				whoisGuard := "NOTPRESENT"
+				if d.WhoIsGuard {
+					whoisGuard = "ENABLED"
+				}
+				response.Domains = append(response.Domains, namecheapDomain{
+					ID:         strconv.Itoa((page-1)*pageSize + i + 1),
+					Name:       d.Name,
+					User:       "test",
+					Created:    d.Created.Format("01/02/2006"),
+					Expires:    d.Expires.Format("01/02/2006"),
+					IsExpired:  d.IsExpired,
+					IsLocked:   d.IsLocked,
+					AutoRenew:  d.AutoRenew,
+					WhoisGuard: whoisGuard,
+					IsOurDNS:   d.IsOurDNS,
+				})
+			}
+			namecheapSuccess(w, response)
+
+		case "namecheap.domains.dns.getHosts":
+			records, ok := r.domainRecords(domain)
+			if !ok {
+				namecheapFail(w, "2019166", "Domain not found")
+				return
+			}
+
+			result := &namecheapHostsResult{Domain: domain, EmailType: "NONE", IsUsingOurDNS: true}
+			for _, x := range records {
+				host := namecheapHost{HostId: x.HostId, Name: x.Name, Type: x.Type, Address: x.Address, TTL: x.TTL, MXPref: "10"}
+				if host.TTL == "" {
+					host.TTL = "1799"
+				}
+				if x.Type == "MX" && x.Priority != "" {
+					host.MXPref = x.Priority
+				}
+				if x.Type == "MX" {
+					result.EmailType = "MX"
+				}
+				result.Hosts = append(result.Hosts, host)
+			}
+			namecheapSuccess(w, &namecheapCommandResponse{Type: command, Hosts: result})
+
+		case "namecheap.domains.dns.setHosts":
+			var records []types.Record
+			for i := 1; req.Form.Has("HostName" + strconv.Itoa(i)); i++ {
+				n := strconv.Itoa(i)
+				record := types.Record{
+					Name:    req.Form.Get("HostName" + n),
+					Type:    req.Form.Get("RecordType" + n),
+					Address: req.Form.Get("Address" + n),
+					TTL:     req.Form.Get("TTL" + n),
+				}
+				if record.TTL == "" {
+					record.TTL = "1799"
+				}
+				if record.Type == "MX" {
+					if req.Form.Get("EmailType") != "MX" {
+						namecheapFail(w, "2050900", "MX records are only allowed when EmailType is MX")
+						return
+					}
+					record.Priority = req.Form.Get("MXPref" + n)
+				}
+				records = append(records, record)
+			}
+
+			if !r.setRecords(domain, records) {
+				namecheapFail(w, "2019166", "Domain not found")
+				return
+			}
+			namecheapSuccess(w, &namecheapCommandResponse{
+				Type:     command,
+				SetHosts: &namecheapSetHostsResult{Domain: domain, IsSuccess: true},
+			})
+
+		default:
+			namecheapFail(w, "1010900", "Invalid command "+strings.TrimSpace(command))
+		}
+	})
+
+	return r.wrap(handler, func(w http.ResponseWriter, failure Failure) {
+		if failure == FailAuth {
+			namecheapFail(w, "1011102", "API Key is invalid or API access has not been enabled")
+			return
+		}
+		// Namecheap throttles requests with a 405
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+}
+
+func namecheapSuccess(w http.ResponseWriter, command *namecheapCommandResponse) {
+	writeXML(w, namecheapResponse{Status: "OK", CommandResponse: command})
+}
+
+// Namecheap returns errors with a 200 status and the error number in the body
+func namecheapFail(w http.ResponseWriter, number string, message string) {
+	writeXML(w, namecheapResponse{Status: "ERROR", Errors: []namecheapError{{Number: number, Message: message}}})
+}
+
+func writeXML(w http.ResponseWriter, response any) {
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,76 @@
+package providertest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// API key accepted by the fake Palo Alto firewall
+const PaloAltoAPIKey = "LUFRPT1fake"
+
+var paloAltoTestURL = regexp.MustCompile(`^<test><url>(.+)</url></test>$`)
+
+type paloAltoResponse struct {
+	XMLName xml.Name       `xml:"response"`
+	Status  string         `xml:"status,attr"`
+	Code    string         `xml:"code,attr,omitempty"`
+	Result  paloAltoResult `xml:"result"`
+}
+
+type paloAltoResult struct {
+	Text string `xml:",chardata"`
+	Msg  string `xml:"msg,omitempty"`
+}
+
+// PaloAltoHandler serves the XML API of a Palo Alto firewall for the "test url" command, the
+// base URL of the client is the URL of the server
+func PaloAltoHandler(c *Categories) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/", func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get("X-PAN-KEY")
+		if key == "" {
+			key = req.URL.Query().Get("key")
+		}
+		if key != PaloAltoAPIKey {
+			paloAltoError(w, http.StatusForbidden, "403", "Invalid credentials.")
+			return
+		}
+
+		match := paloAltoTestURL.FindStringSubmatch(req.URL.Query().Get("cmd"))
+		if req.URL.Query().Get("type") != "op" || match == nil {
+			paloAltoError(w, http.StatusBadRequest, "17", "Invalid command")
+			return
+		}
+
+		domain := match[1]
+		category := c.lookup(domain)
+		if category == "" {
+			category = "unknown"
+		}
+
+		result := fmt.Sprintf("%s %s low-risk (Base db) expires in 2000 seconds\n", domain, category)
+		result += fmt.Sprintf("%s %s low-risk (Cloud db)\n", domain, category)
+		writePaloAlto(w, http.StatusOK, paloAltoResponse{Status: "success", Result: paloAltoResult{Text: result}})
+	})
+
+	return c.wrap(mux, func(w http.ResponseWriter, failure Failure) {
+		if failure == FailAuth {
+			paloAltoError(w, http.StatusForbidden, "403", "Invalid credentials.")
+			return
+		}
+		paloAltoError(w, http.StatusTooManyRequests, "22", "Too many requests")
+	})
+}
+
+func paloAltoError(w http.ResponseWriter, status int, code string, message string) {
+	writePaloAlto(w, status, paloAltoResponse{Status: "error", Code: code, Result: paloAltoResult{Msg: message}})
+}
+
+func writePaloAlto(w http.ResponseWriter, status int, response paloAltoResponse) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(response)
+}
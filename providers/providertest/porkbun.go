@@ -0,0 +1,201 @@
+package providertest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/lum8rjack/redcompass/services/types"
+)
+
+// Porkbun returns at most 1000 domains per domain/listAll request
+const porkbunPageSize = 1000
+
+// Body of the Porkbun requests, the fields that are not used by a command are empty
+type porkbunRequest struct {
+	ApiKey       string `json:"apikey"`
+	SecretApiKey string `json:"secretapikey"`
+	Start        string `json:"start"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Content      string `json:"content"`
+	TTL          string `json:"ttl"`
+	Prio         string `json:"prio"`
+}
+
+// PorkbunHandler serves the Porkbun v3 JSON API for the registrar, the base URL of the
+// client is the URL of the server
+func PorkbunHandler(r *Registrar) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /domain/listAll", func(w http.ResponseWriter, req *http.Request) {
+		body, ok := porkbunBody(w, req)
+		if !ok {
+			return
+		}
+
+		start, _ := strconv.Atoi(body.Start)
+		domains := r.domainList()
+		page, _ := paginate(domains, start/porkbunPageSize+1, porkbunPageSize)
+
+		result := []map[string]any{}
+		for _, d := range page {
+			whoisPrivacy := "0"
+			if d.WhoIsGuard {
+				whoisPrivacy = "1"
+			}
+			autoRenew := 0
+			if d.AutoRenew {
+				autoRenew = 1
+			}
+			result = append(result, map[string]any{
+				"domain":       d.Name,
+				"status":       "ACTIVE",
+				"createDate":   d.Created.Format("2006-01-02 15:04:05"),
+				"expireDate":   d.Expires.Format("2006-01-02 15:04:05"),
+				"whoisPrivacy": whoisPrivacy,
+				"autoRenew":    autoRenew,
+			})
+		}
+		porkbunSuccess(w, map[string]any{"domains": result})
+	})
+
+	mux.HandleFunc("POST /domain/getNs/{domain}", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := porkbunBody(w, req); !ok {
+			return
+		}
+
+		d, ok := r.domain(req.PathValue("domain"))
+		if !ok {
+			porkbunError(w, http.StatusBadRequest, "Invalid domain.")
+			return
+		}
+
+		ns := []string{"ns1.example.net", "ns2.example.net"}
+		if d.IsOurDNS {
+			ns = []string{"curitiba.ns.porkbun.com", "fortaleza.ns.porkbun.com"}
+		}
+		porkbunSuccess(w, map[string]any{"ns": ns})
+	})
+
+	mux.HandleFunc("POST /dns/retrieve/{domain}", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := porkbunBody(w, req); !ok {
+			return
+		}
+
+		domain := req.PathValue("domain")
+		records, ok := r.domainRecords(domain)
+		if !ok {
+			porkbunError(w, http.StatusBadRequest, "Invalid domain.")
+			return
+		}
+
+		result := []map[string]any{}
+		for _, x := range records {
+			prio := "0"
+			if x.Priority != "" {
+				prio = x.Priority
+			}
+			result = append(result, map[string]any{
+				"id":      x.HostId,
+				"name":    fullName(x.Name, domain),
+				"type":    x.Type,
+				"content": x.Address,
+				"ttl":     x.TTL,
+				"prio":    prio,
+			})
+		}
+		porkbunSuccess(w, map[string]any{"records": result})
+	})
+
+	mux.HandleFunc("POST /dns/create/{domain}", func(w http.ResponseWriter, req *http.Request) {
+		body, ok := porkbunBody(w, req)
+		if !ok {
+			return
+		}
+
+		record, ok := r.createRecord(req.PathValue("domain"), porkbunRecord(body))
+		if !ok {
+			porkbunError(w, http.StatusBadRequest, "Invalid domain.")
+			return
+		}
+
+		id, _ := strconv.Atoi(record.HostId)
+		porkbunSuccess(w, map[string]any{"id": id})
+	})
+
+	mux.HandleFunc("POST /dns/edit/{domain}/{id}", func(w http.ResponseWriter, req *http.Request) {
+		body, ok := porkbunBody(w, req)
+		if !ok {
+			return
+		}
+
+		if _, ok := r.updateRecord(req.PathValue("domain"), req.PathValue("id"), porkbunRecord(body)); !ok {
+			porkbunError(w, http.StatusBadRequest, "Edit error: Invalid record ID.")
+			return
+		}
+		porkbunSuccess(w, map[string]any{})
+	})
+
+	mux.HandleFunc("POST /dns/delete/{domain}/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := porkbunBody(w, req); !ok {
+			return
+		}
+
+		if !r.deleteRecord(req.PathValue("domain"), req.PathValue("id")) {
+			porkbunError(w, http.StatusBadRequest, "Delete error: Invalid record ID.")
+			return
+		}
+		porkbunSuccess(w, map[string]any{})
+	})
+
+	return r.wrap(mux, func(w http.ResponseWriter, failure Failure) {
+		if failure == FailAuth {
+			porkbunError(w, http.StatusBadRequest, "Invalid API key. (002)")
+			return
+		}
+		porkbunError(w, http.StatusTooManyRequests, "Rate limit exceeded, too many requests.")
+	})
+}
+
+// Decode the request body and check the keys are set
+func porkbunBody(w http.ResponseWriter, req *http.Request) (porkbunRequest, bool) {
+	var body porkbunRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		porkbunError(w, http.StatusBadRequest, "Invalid JSON.")
+		return body, false
+	}
+
+	if body.ApiKey == "" || body.SecretApiKey == "" {
+		porkbunError(w, http.StatusBadRequest, "Invalid API key. (001)")
+		return body, false
+	}
+
+	return body, true
+}
+
+// Convert the body of a create or edit request to a record
+func porkbunRecord(body porkbunRequest) types.Record {
+	return types.Record{
+		Name:     body.Name,
+		Type:     body.Type,
+		Address:  body.Content,
+		TTL:      body.TTL,
+		Priority: body.Prio,
+	}
+}
+
+func porkbunSuccess(w http.ResponseWriter, response map[string]any) {
+	response["status"] = "SUCCESS"
+	writeJSON(w, http.StatusOK, response)
+}
+
+func porkbunError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"status": "ERROR", "message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,78 @@
+// Package providertest provides fake registrar and scanner APIs backed by httptest and a
+// conformance suite that every types.Service and types.Scanner implementation must pass.
+package providertest
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Failure returned by a fake API instead of the normal response
+type Failure int
+
+const (
+	NoFailure Failure = iota
+
+	// The credentials are rejected
+	FailAuth
+
+	// The request is rate limited with a Retry-After longer than the shared client waits
+	FailRateLimit
+
+	// The API is temporarily unavailable, the shared client retries the request
+	FailUnavailable
+)
+
+// Retry-After in seconds sent with rate limited responses
+const RateLimitRetryAfter = 120
+
+// Failures to inject into the responses of a fake API
+type faults struct {
+	mu        sync.Mutex
+	failure   Failure
+	remaining int
+}
+
+// Fail makes the next n requests fail, a negative n fails every request until Fail is called again
+func (f *faults) Fail(failure Failure, n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failure = failure
+	f.remaining = n
+}
+
+// Get the failure for the next request
+func (f *faults) next() Failure {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failure == NoFailure || f.remaining == 0 {
+		return NoFailure
+	}
+	if f.remaining > 0 {
+		f.remaining--
+	}
+	return f.failure
+}
+
+// Wrap a handler so injected failures are written with the provider specific response
+func (f *faults) wrap(next http.Handler, writeFailure func(http.ResponseWriter, Failure)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failure := f.next()
+		if failure == NoFailure {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if failure == FailUnavailable {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if failure == FailRateLimit {
+			w.Header().Set("Retry-After", strconv.Itoa(RateLimitRetryAfter))
+		}
+		writeFailure(w, failure)
+	})
+}
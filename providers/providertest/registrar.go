@@ -0,0 +1,173 @@
+package providertest
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lum8rjack/redcompass/services/types"
+)
+
+// Registrar holds the domains and records served by the fake registrar APIs. Record names are
+// stored relative to the domain, "@" is the domain itself, and each fake returns them in the
+// format of its provider.
+type Registrar struct {
+	faults
+
+	mu      sync.Mutex
+	domains []types.Domain
+	records map[string][]types.Record
+	nextID  int
+}
+
+// NewRegistrar creates a registrar without any domains
+func NewRegistrar() *Registrar {
+	return &Registrar{records: map[string][]types.Record{}, nextID: 1000}
+}
+
+// AddDomain adds a domain and its records, the records are given new host ids
+func (r *Registrar) AddDomain(domain types.Domain, records ...types.Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.domains = append(r.domains, domain)
+	r.records[domain.Name] = []types.Record{}
+	for _, record := range records {
+		r.addRecord(domain.Name, record)
+	}
+}
+
+// Records returns the records for a domain
+func (r *Registrar) Records(domain string) []types.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.records[domain])
+}
+
+// Get a copy of every domain
+func (r *Registrar) domainList() []types.Domain {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.domains)
+}
+
+// Get a domain by name
+func (r *Registrar) domain(name string) (types.Domain, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.domains {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return types.Domain{}, false
+}
+
+// Get the records for a domain, false if the domain does not exist
+func (r *Registrar) domainRecords(domain string) ([]types.Record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	records, ok := r.records[domain]
+	return slices.Clone(records), ok
+}
+
+// Add a record to a domain, the lock must be held
+func (r *Registrar) addRecord(domain string, record types.Record) types.Record {
+	record.Domain = domain
+	record.Name = relativeName(record.Name, domain)
+	record.HostId = strconv.Itoa(r.nextID)
+	r.nextID++
+
+	r.records[domain] = append(r.records[domain], record)
+	return record
+}
+
+// Create a record, false if the domain does not exist
+func (r *Registrar) createRecord(domain string, record types.Record) (types.Record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[domain]; !ok {
+		return record, false
+	}
+	return r.addRecord(domain, record), true
+}
+
+// Update the record with the host id, false if it does not exist
+func (r *Registrar) updateRecord(domain string, hostID string, record types.Record) (types.Record, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, x := range r.records[domain] {
+		if x.HostId == hostID {
+			record.Domain = domain
+			record.Name = relativeName(record.Name, domain)
+			record.HostId = hostID
+			r.records[domain][i] = record
+			return record, true
+		}
+	}
+	return record, false
+}
+
+// Delete the record with the host id, false if it does not exist
+func (r *Registrar) deleteRecord(domain string, hostID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, x := range r.records[domain] {
+		if x.HostId == hostID {
+			r.records[domain] = slices.Delete(r.records[domain], i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// Replace every record of a domain, the records are given new host ids
+func (r *Registrar) setRecords(domain string, records []types.Record) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.records[domain]; !ok {
+		return false
+	}
+
+	r.records[domain] = []types.Record{}
+	for _, record := range records {
+		r.addRecord(domain, record)
+	}
+	return true
+}
+
+// Get the name of a record relative to the domain
+func relativeName(name string, domain string) string {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" || name == domain {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+// Get the full name of a record
+func fullName(name string, domain string) string {
+	if name == "@" {
+		return domain
+	}
+	return name + "." + domain
+}
+
+// Get the page of items for a 1-based page number
+func paginate[T any](items []T, page int, perPage int) ([]T, int) {
+	totalPages := (len(items) + perPage - 1) / perPage
+	start := (page - 1) * perPage
+	if page < 1 || start >= len(items) {
+		return []T{}, totalPages
+	}
+	return items[start:min(start+perPage, len(items))], totalPages
+}
@@ -0,0 +1,44 @@
+package providertest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TalosHandler serves the lookup API of the Talos reputation center, the base URL of the client
+// is the URL of the server
+func TalosHandler(c *Categories) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /sb_api/query_lookup", func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		if query.Get("query") != "/api/v2/details/domain/" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown query"})
+			return
+		}
+
+		domain := query.Get("query_entry")
+		var category any
+		if description := c.lookup(domain); description != "" {
+			category = map[string]string{"description": description, "long_description": description}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"category": category, "web_score_name": "Neutral"})
+	})
+
+	// Lookups are only answered for requests from the reputation center
+	fromReputationCenter := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.Header.Get("Referer"), "/reputation_center/") {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+
+	return c.wrap(fromReputationCenter, func(w http.ResponseWriter, failure Failure) {
+		if failure == FailAuth {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "too many requests"})
+	})
+}
@@ -0,0 +1,178 @@
+package providertest
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Daily requests allowed by the fake VirusTotal API unless SetAllowed is called, the limit of the public API
+const virusTotalDailyLimit = 500
+
+// Analysis stats for a domain returned by a fake scanner
+type ScanResult struct {
+	Malicious  int
+	Suspicious int
+	Harmless   int
+	Undetected int
+
+	// Category reported by each vendor
+	Categories map[string]string
+}
+
+// JARM fingerprint and certificate returned for every domain by the fake VirusTotal API
+const (
+	VirusTotalJarm           = "27d40d40d29d40d1dc42d43d00041d4689ee210389f4f6b4b5b1b93f92252d"
+	VirusTotalCertIssuer     = "R11"
+	VirusTotalCertNotBefore  = "2025-01-01 00:00:00"
+	VirusTotalCertNotAfter   = "2025-04-01 00:00:00"
+	VirusTotalDNSRecordValue = "192.0.2.10"
+)
+
+// Scans holds the results and quota usage served by the fake scanner APIs
+type Scans struct {
+	faults
+
+	mu      sync.Mutex
+	results map[string]ScanResult
+	used    int
+	allowed int
+}
+
+// NewScans creates a scanner without any results
+func NewScans() *Scans {
+	return &Scans{results: map[string]ScanResult{}, allowed: virusTotalDailyLimit}
+}
+
+// SetResult sets the result returned for a domain
+func (s *Scans) SetResult(domain string, result ScanResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[domain] = result
+}
+
+// SetUsed sets the number of lookups already used today
+func (s *Scans) SetUsed(used int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.used = used
+}
+
+// SetAllowed sets the number of lookups allowed per day
+func (s *Scans) SetAllowed(allowed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.allowed = allowed
+}
+
+// Look up the result for a domain and count it against the quota
+func (s *Scans) lookup(domain string) (ScanResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok := s.results[domain]
+	if ok {
+		s.used++
+	}
+	return result, ok
+}
+
+func (s *Scans) usage() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.used
+}
+
+func (s *Scans) limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.allowed
+}
+
+// VirusTotalHandler serves the VirusTotal v3 API for the scanner, the base URL of the client
+// is the URL of the server
+func VirusTotalHandler(s *Scans) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /metadata", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{"engines": map[string]any{}}})
+	})
+
+	mux.HandleFunc("GET /users/{user}/overall_quotas", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"data": map[string]any{
+				"api_requests_daily": map[string]any{
+					"user": map[string]int{"used": s.usage(), "allowed": s.limit()},
+				},
+			},
+		})
+	})
+
+	mux.HandleFunc("GET /domains/{domain}", func(w http.ResponseWriter, req *http.Request) {
+		domain := req.PathValue("domain")
+		result, ok := s.lookup(domain)
+		if !ok {
+			virusTotalError(w, http.StatusNotFound, "NotFoundError", "Domain \""+domain+"\" not found")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"data": map[string]any{
+				"id":   domain,
+				"type": "domain",
+				"attributes": map[string]any{
+					"last_analysis_date": time.Now().Unix(),
+					"last_analysis_stats": map[string]int{
+						"malicious":  result.Malicious,
+						"suspicious": result.Suspicious,
+						"harmless":   result.Harmless,
+						"undetected": result.Undetected,
+						"timeout":    0,
+					},
+					"last_analysis_results": map[string]any{},
+					"total_votes":           map[string]int{"harmless": 0, "malicious": 0},
+					"categories":            result.Categories,
+					"jarm":                  VirusTotalJarm,
+					"last_https_certificate": map[string]any{
+						"issuer":   map[string]string{"CN": VirusTotalCertIssuer, "O": "Let's Encrypt", "C": "US"},
+						"subject":  map[string]string{"CN": domain},
+						"validity": map[string]string{"not_before": VirusTotalCertNotBefore, "not_after": VirusTotalCertNotAfter},
+					},
+					"last_dns_records": []map[string]any{
+						{"type": "A", "ttl": 600, "value": VirusTotalDNSRecordValue},
+					},
+				},
+			},
+		})
+	})
+
+	// Every request needs the API key
+	authenticated := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("x-apikey") == "" {
+			virusTotalError(w, http.StatusUnauthorized, "AuthenticationRequiredError", "X-Apikey header is missing")
+			return
+		}
+		if s.usage() >= s.limit() {
+			virusTotalError(w, http.StatusTooManyRequests, "QuotaExceededError", "Quota exceeded")
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+
+	return s.wrap(authenticated, func(w http.ResponseWriter, failure Failure) {
+		if failure == FailAuth {
+			virusTotalError(w, http.StatusUnauthorized, "WrongCredentialsError", "Wrong API key")
+			return
+		}
+		virusTotalError(w, http.StatusTooManyRequests, "QuotaExceededError", "Quota exceeded")
+	})
+}
+
+func virusTotalError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]string{"code": code, "message": message}})
+}
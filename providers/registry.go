@@ -0,0 +1,125 @@
+package providers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	scannertypes "github.com/lum8rjack/redcompass/scanners/types"
+	servicetypes "github.com/lum8rjack/redcompass/services/types"
+)
+
+// Kind of provider, registrars sync domains and records, scanners check the reputation of domains
+// and checkers look up the category web filtering vendors give them
+type Kind string
+
+const (
+	KindRegistrar Kind = "registrar"
+	KindScanner   Kind = "scanner"
+	KindChecker   Kind = "checker"
+)
+
+// Provider registered by a service or scanner package. The name is the Provider saved on the
+// Services records and the settings are described by a JSON schema used by the Settings page.
+type Provider struct {
+	Name     string          `json:"name"`
+	Kind     Kind            `json:"kind"`
+	Settings json.RawMessage `json:"settings"`
+
+	// Create a client from the settings of a Services record, only the constructor for the kind is set
+	NewService func(settings string) (servicetypes.Service, error) `json:"-"`
+	NewScanner func(settings string) (scannertypes.Scanner, error) `json:"-"`
+	NewChecker func(settings string) (scannertypes.Checker, error) `json:"-"`
+
+	// Saves the results of a scanner that does not return results in the VirusTotal format
+	Store ResultStore `json:"-"`
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Provider{}
+)
+
+// Register a provider, the name must be unique and the constructor must match the kind
+func Register(p Provider) error {
+	if p.Name == "" {
+		return errors.New("provider name is empty")
+	}
+
+	switch p.Kind {
+	case KindRegistrar:
+		if p.NewService == nil {
+			return fmt.Errorf("registrar %s has no service constructor", p.Name)
+		}
+	case KindScanner:
+		if p.NewScanner == nil {
+			return fmt.Errorf("scanner %s has no scanner constructor", p.Name)
+		}
+	case KindChecker:
+		if p.NewChecker == nil {
+			return fmt.Errorf("checker %s has no checker constructor", p.Name)
+		}
+	default:
+		return fmt.Errorf("provider %s has an invalid kind %q", p.Name, p.Kind)
+	}
+
+	if len(p.Settings) == 0 {
+		p.Settings = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	if !json.Valid(p.Settings) {
+		return fmt.Errorf("provider %s has an invalid settings schema", p.Name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[p.Name]; ok {
+		return fmt.Errorf("provider %s is already registered", p.Name)
+	}
+	registry[p.Name] = p
+	return nil
+}
+
+// MustRegister registers a provider and panics if it is invalid, it is used by the provider
+// packages in their init function
+func MustRegister(p Provider) {
+	if err := Register(p); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the provider registered with the name
+func Lookup(name string) (Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	p, ok := registry[name]
+	return p, ok
+}
+
+// List returns the registered providers of a kind sorted by name, every provider is returned
+// when the kind is empty
+func List(kind Kind) []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	list := []Provider{}
+	for _, p := range registry {
+		if kind == "" || p.Kind == kind {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Names returns the names of the registered providers of a kind sorted by name
+func Names(kind Kind) []string {
+	var names []string
+	for _, p := range List(kind) {
+		names = append(names, p.Name)
+	}
+	return names
+}
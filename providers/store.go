@@ -0,0 +1,22 @@
+package providers
+
+import (
+	"time"
+
+	"github.com/lum8rjack/redcompass/health"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ResultStore saves the results of a scanner to its own collections. Scanners without a store
+// return results in the VirusTotal format, which are saved to the VirusTotal collections.
+type ResultStore interface {
+	// Save the results a scanner returned for a domain, the name of the domain is returned
+	SaveResults(app core.App, results []byte) (string, error)
+
+	// Time of the last scan of each domain by name, used to scan the least recently scanned
+	// domains first
+	LastScans(app core.App) (map[string]time.Time, error)
+
+	// Latest results of a domain evaluated by the health policy
+	HealthResults(app core.App, domain *core.Record) (health.Results, error)
+}
@@ -5,12 +5,13 @@ import (
 	"time"
 
 	"github.com/lum8rjack/redcompass/scanners/virustotal"
+	"github.com/lum8rjack/redcompass/services/types"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 )
 
-// Add domain record to the database
-func AddDomain(domainProvider string, domainName string, purchasedDate time.Time, expirationDate time.Time, isExpired bool, autoRenew bool, isLocked bool, customDNS bool) error {
+// Add domain record to the database and link it to the service record it came from
+func AddDomain(service *core.Record, domainName string, purchasedDate time.Time, expirationDate time.Time, isExpired bool, autoRenew bool, isLocked bool, customDNS bool) error {
 	domainsCollection, err := app.FindCollectionByNameOrId("Domains")
 	if err != nil {
 		return err
@@ -29,8 +30,14 @@ func AddDomain(domainProvider string, domainName string, purchasedDate time.Time
 	record.Set("Is_Expired", isExpired)
 	record.Set("Auto_Renew", autoRenew)
 	record.Set("Is_Locked", isLocked)
-	record.Set("Domain_Provider", domainProvider)
+	record.Set("Domain_Provider", service.GetString("Provider"))
+	record.Set("Service", service.Id)
 	record.Set("Custom_DNS", customDNS)
+
+	// A domain that is back at the provider is no longer removed
+	record.Set("Removed_From_Provider", false)
+	record.Set("Removed_Date", "")
+
 	err = app.Save(record)
 	if err != nil {
 		return err
@@ -39,68 +46,289 @@ func AddDomain(domainProvider string, domainName string, purchasedDate time.Time
 	return nil
 }
 
-// Add a new record to a domain
-func AddDomainRecord(domainName string, recordName string, recordType string, address string) error {
+// Flag the domains of a service that are no longer returned by the provider, for example after
+// a transfer out. The domains are kept so they stay visible in the project history through
+// Last_Used, but they are no longer scanned or alerted on. The names of the flagged domains are returned.
+func FlagRemovedDomains(service *core.Record, domains []types.Domain) ([]string, error) {
+	var removed []string
+
+	existing, err := app.FindAllRecords("Domains",
+		dbx.NewExp("Service = {:service}", dbx.Params{"service": service.Id}),
+		dbx.NewExp("Removed_From_Provider = {:removed}", dbx.Params{"removed": false}),
+	)
+	if err != nil {
+		return removed, err
+	}
+
+	present := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		present[d.Name] = true
+	}
+
+	now := time.Now().UTC()
+	for _, record := range existing {
+		if present[record.GetString("Name")] {
+			continue
+		}
+
+		record.Set("Removed_From_Provider", true)
+		record.Set("Removed_Date", now)
+		if err := app.Save(record); err != nil {
+			return removed, err
+		}
+		removed = append(removed, record.GetString("Name"))
+	}
+
+	return removed, nil
+}
+
+// A change made to a domain's records during a sync
+type RecordChange struct {
+	Action string
+	Old    *types.Record
+	New    *types.Record
+}
+
+const (
+	RecordAdded    = "added"
+	RecordModified = "modified"
+	RecordRemoved  = "removed"
+)
+
+// Sync the records for a domain with the records from the provider. Existing records are
+// matched by HostId, or by name, type and address, and only the records that changed are
+// inserted, updated or deleted. All changes for the domain, and the history of those changes,
+// are applied in a single transaction.
+func SyncDomainRecords(provider string, domainName string, records []types.Record) ([]RecordChange, error) {
+	var changes []RecordChange
+
 	// Get domain record id
 	domainRecord, err := app.FindFirstRecordByData("Domains", "Name", domainName)
 	if err != nil {
-		return err
+		return changes, err
+	}
+
+	err = app.RunInTransaction(func(txApp core.App) error {
+		changes = nil
+
+		recordsCollection, err := txApp.FindCollectionByNameOrId("Domain_Records")
+		if err != nil {
+			return err
+		}
+
+		existing, err := txApp.FindAllRecords("Domain_Records",
+			dbx.NewExp("Domain = {:domain}", dbx.Params{"domain": domainRecord.Id}),
+		)
+		if err != nil {
+			return err
+		}
+
+		matched := make(map[string]bool)
+		for _, r := range records {
+			record := matchDomainRecord(existing, matched, r)
+
+			// Add new records
+			if record == nil {
+				record = core.NewRecord(recordsCollection)
+				record.Set("Domain", domainRecord.Id)
+				setDomainRecordFields(record, r)
+				if err := txApp.Save(record); err != nil {
+					return err
+				}
+				changes = append(changes, RecordChange{Action: RecordAdded, New: &r})
+				continue
+			}
+			matched[record.Id] = true
+
+			old := domainRecordFromRecord(domainName, record)
+			modified := old.Name != r.Name || old.Type != r.Type || old.Address != r.Address
+			settingsChanged := old.TTL != r.TTL || old.Proxied != r.Proxied
+			priorityChanged := old.Priority != r.Priority
+			if !modified && !settingsChanged && !priorityChanged && old.HostId == r.HostId {
+				continue
+			}
+
+			// Records saved before the TTL or priority were stored have none, filling them in is not a change
+			if (old.TTL != "" && settingsChanged) || (old.Priority != "" && priorityChanged) {
+				modified = true
+			}
+
+			// Update the record if anything changed, a new HostId alone is not reported as a change
+			setDomainRecordFields(record, r)
+			if err := txApp.Save(record); err != nil {
+				return err
+			}
+			if modified {
+				changes = append(changes, RecordChange{Action: RecordModified, Old: &old, New: &r})
+			}
+		}
+
+		// Delete records that no longer exist at the provider
+		for _, record := range existing {
+			if matched[record.Id] {
+				continue
+			}
+			if err := txApp.Delete(record); err != nil {
+				return err
+			}
+			old := domainRecordFromRecord(domainName, record)
+			changes = append(changes, RecordChange{Action: RecordRemoved, Old: &old})
+		}
+
+		// Keep a history of every change
+		for _, change := range changes {
+			if err := addDomainRecordChange(txApp, provider, domainRecord, change); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	recordsCollection, err := app.FindCollectionByNameOrId("Domain_Records")
+	if domainRecord.GetString("Assigned_Project") != "" && len(changes) > 0 {
+		notifyRecordDrift(domainRecord, provider, changes)
+	}
+
+	return changes, nil
+}
+
+// Add a change to the Domain_Record_Changes history. A change to a domain that is assigned
+// to a project was not expected, so it is flagged as drift and logged as a warning, the
+// record_drift notification is sent once the sync is committed.
+func addDomainRecordChange(txApp core.App, provider string, domainRecord *core.Record, change RecordChange) error {
+	changesCollection, err := txApp.FindCollectionByNameOrId("Domain_Record_Changes")
 	if err != nil {
 		return err
 	}
 
-	record := core.NewRecord(recordsCollection)
-	record.Set("Domain", domainRecord.Get("id").(string))
-	record.Set("Record_Name", recordName)
-	record.Set("Record_Type", recordType)
-	record.Set("Address", address)
-	err = app.Save(record)
+	r := change.New
+	if r == nil {
+		r = change.Old
+	}
+
+	project := domainRecord.GetString("Assigned_Project")
+
+	record := core.NewRecord(changesCollection)
+	record.Set("Domain", domainRecord.Id)
+	record.Set("Provider", provider)
+	record.Set("Action", change.Action)
+	record.Set("Record_Name", r.Name)
+	record.Set("Record_Type", r.Type)
+	record.Set("Old_Value", recordChangeValue(change.Old))
+	record.Set("New_Value", recordChangeValue(change.New))
+	record.Set("Drift", project != "")
+	record.Set("Assigned_Project", project)
+	err = txApp.Save(record)
 	if err != nil {
 		return err
 	}
 
+	if project != "" {
+		txApp.Logger().Warn("DRIFT:"+domainRecord.GetString("Name")+" record changed on an assigned domain",
+			"projectID", project, "provider", provider, "action", change.Action, "recordName", r.Name, "recordType", r.Type)
+	}
+
 	return nil
 }
 
-// Delete all records for a domain
-func DeleteAllDomainRecords(domainName string) error {
-	// Get domain record id
-	domainRecord, err := app.FindFirstRecordByData("Domains", "Name", domainName)
-	if err != nil {
-		return err
+// The stored value of a record in the change history
+func recordChangeValue(r *types.Record) map[string]any {
+	if r == nil {
+		return nil
 	}
 
-	records, _ := app.FindAllRecords("Domain_Records",
-		dbx.NewExp("Domain = {:domain}", dbx.Params{"domain": domainRecord.Get("id").(string)}),
-	)
+	return map[string]any{
+		"Host_Id":     r.HostId,
+		"Record_Name": r.Name,
+		"Record_Type": r.Type,
+		"Address":     r.Address,
+		"TTL":         r.TTL,
+		"Priority":    r.Priority,
+		"Proxied":     r.Proxied,
+	}
+}
 
-	if len(records) > 0 {
-		for _, record := range records {
-			err = app.Delete(record)
-			if err != nil {
-				return err
+// Find the existing record that matches a provider record, first by HostId and then by name, type and address
+func matchDomainRecord(existing []*core.Record, matched map[string]bool, r types.Record) *core.Record {
+	if r.HostId != "" {
+		for _, record := range existing {
+			if !matched[record.Id] && record.GetString("Host_Id") == r.HostId {
+				return record
 			}
 		}
 	}
 
+	for _, record := range existing {
+		if matched[record.Id] {
+			continue
+		}
+		if record.GetString("Record_Name") == r.Name && record.GetString("Record_Type") == r.Type && record.GetString("Address") == r.Address {
+			return record
+		}
+	}
+
 	return nil
 }
 
-// Add VirusTotal record to the database
-func AddVirusTotalRecord(vtresults virustotal.VT_Database) error {
+// Set the Domain_Records fields from a provider record
+func setDomainRecordFields(record *core.Record, r types.Record) {
+	record.Set("Host_Id", r.HostId)
+	record.Set("Record_Name", r.Name)
+	record.Set("Record_Type", r.Type)
+	record.Set("Address", r.Address)
+	record.Set("TTL", r.TTL)
+	record.Set("Priority", r.Priority)
+	record.Set("Proxied", r.Proxied)
+}
+
+// Convert a Domain_Records record to a provider record
+func domainRecordFromRecord(domainName string, record *core.Record) types.Record {
+	return types.Record{
+		Domain:   domainName,
+		HostId:   record.GetString("Host_Id"),
+		Name:     record.GetString("Record_Name"),
+		Type:     record.GetString("Record_Type"),
+		Address:  record.GetString("Address"),
+		TTL:      record.GetString("TTL"),
+		Priority: record.GetString("Priority"),
+		Proxied:  record.GetBool("Proxied"),
+	}
+}
+
+// Add VirusTotal record to the database, the scanner is the provider that returned the results
+func AddVirusTotalRecord(scanner string, vtresults virustotal.VT_Database) error {
 	virustotalCollection, err := app.FindCollectionByNameOrId("VirusTotal")
 	if err != nil {
 		return err
 	}
 
-	record, err := app.FindFirstRecordByData("VirusTotal", "Domain", vtresults.Domain)
+	// Each scanner keeps its own latest result so scanners do not overwrite each other
+	record, err := app.FindFirstRecordByFilter("VirusTotal", "Scanner = {:scanner} && Domain = {:domain}",
+		dbx.Params{"scanner": scanner, "domain": vtresults.Domain},
+	)
 	if err != nil {
 		record = core.NewRecord(virustotalCollection)
+		record.Set("Scanner", scanner)
 		record.Set("Domain", vtresults.Domain)
 	}
+	isNew := record.IsNew()
+	previousDetections := record.GetInt("Malicious") + record.GetInt("Suspicious")
+
+	// Engine verdicts of the previous scan of the scanner, used to record which engines changed
+	previousResults := map[string]virustotal.VT_Analysis_Engine_Result{}
+	if !isNew {
+		if err := record.UnmarshalJSONField("Last_Analysis_Results", &previousResults); err != nil {
+			return err
+		}
+
+		// Keep the previous scan when it was saved before the history was recorded
+		if err := backfillVirusTotalScan(record, previousResults); err != nil {
+			return err
+		}
+	}
 
 	lastAnalysisResults, err := json.Marshal(vtresults.LastAnalysisResults)
 	if err != nil {
@@ -116,10 +344,93 @@ func AddVirusTotalRecord(vtresults virustotal.VT_Database) error {
 	record.Set("Harmless", vtresults.Harmless)
 	record.Set("Timeout", vtresults.Timeout)
 	record.Set("Last_Analysis_Results", lastAnalysisResults)
+	record.Set("Categories", vtresults.Categories)
+	record.Set("Certificate_Issuer", vtresults.Certificate.Issuer)
+	record.Set("Certificate_Subject", vtresults.Certificate.Subject)
+	record.Set("Certificate_Not_Before", certificateDate(vtresults.Certificate.NotBefore))
+	record.Set("Certificate_Not_After", certificateDate(vtresults.Certificate.NotAfter))
+	record.Set("JARM", vtresults.Jarm)
+	record.Set("Last_DNS_Records", vtresults.LastDNSRecords)
 	err = app.Save(record)
 	if err != nil {
 		return err
 	}
 
+	changes := []virustotal.VT_Engine_Change{}
+	if !isNew {
+		changes = virustotal.DiffResults(previousResults, vtresults.LastAnalysisResults)
+	}
+	err = AddVirusTotalScan(scanner, vtresults, time.Now().UTC(), changes)
+	if err != nil {
+		return err
+	}
+
+	if detections := vtresults.Malicious + vtresults.Suspicious; !isNew && detections > previousDetections {
+		notifyDetectionsIncreased(vtresults.Domain, previousDetections, detections)
+	}
+
+	if err := AddVirusTotalCategorizations(scanner, vtresults.Domain, vtresults.Categories); err != nil {
+		app.Logger().Error("CATEGORIZATION:"+vtresults.Domain, "function", "AddVirusTotalCategorizations", "error", err.Error())
+	}
+
 	return nil
 }
+
+// Add a scan to the VirusTotal_Scans history with the engines whose verdict changed since the previous scan
+func AddVirusTotalScan(scanner string, vtresults virustotal.VT_Database, scanned time.Time, changes []virustotal.VT_Engine_Change) error {
+	scansCollection, err := app.FindCollectionByNameOrId("VirusTotal_Scans")
+	if err != nil {
+		return err
+	}
+
+	record := core.NewRecord(scansCollection)
+	record.Set("Domain", vtresults.Domain)
+	record.Set("Scanner", scanner)
+	record.Set("Scanned", scanned)
+	record.Set("Votes_Harmless", vtresults.VotesHarmless)
+	record.Set("Votes_Malicious", vtresults.VotesMalicious)
+	record.Set("Malicious", vtresults.Malicious)
+	record.Set("Suspicious", vtresults.Suspicious)
+	record.Set("Undetected", vtresults.Undetected)
+	record.Set("Harmless", vtresults.Harmless)
+	record.Set("Timeout", vtresults.Timeout)
+	record.Set("Last_Analysis_Results", vtresults.LastAnalysisResults)
+	record.Set("Categories", vtresults.Categories)
+	record.Set("Changes", changes)
+
+	return app.Save(record)
+}
+
+// Add the latest result of a scanner for a domain to the history when the scanner has no scans
+// of the domain yet, so the results from before the history was recorded are not lost
+func backfillVirusTotalScan(record *core.Record, results map[string]virustotal.VT_Analysis_Engine_Result) error {
+	total, err := app.CountRecords("VirusTotal_Scans", dbx.HashExp{
+		"Scanner": record.GetString("Scanner"),
+		"Domain":  record.GetString("Domain"),
+	})
+	if err != nil || total > 0 {
+		return err
+	}
+
+	return AddVirusTotalScan(record.GetString("Scanner"), virustotal.VT_Database{
+		Domain:              record.GetString("Domain"),
+		VotesHarmless:       record.GetInt("Votes_Harmless"),
+		VotesMalicious:      record.GetInt("Votes_Malicious"),
+		Malicious:           record.GetInt("Malicious"),
+		Suspicious:          record.GetInt("Suspicious"),
+		Undetected:          record.GetInt("Undetected"),
+		Harmless:            record.GetInt("Harmless"),
+		Timeout:             record.GetInt("Timeout"),
+		LastAnalysisResults: results,
+	}, record.GetDateTime("updated").Time(), []virustotal.VT_Engine_Change{})
+}
+
+// Parse the validity date of a certificate, VirusTotal uses the "2006-01-02 15:04:05" format in
+// UTC. The zero time, saved as an empty date, is returned when the domain has no certificate.
+func certificateDate(value string) time.Time {
+	date, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
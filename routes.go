@@ -0,0 +1,725 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/categorization"
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/scanners/virustotal"
+	"github.com/lum8rjack/redcompass/services"
+	"github.com/lum8rjack/redcompass/services/types"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	pbtypes "github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Body of a request to create or update a DNS record
+type DomainRecordRequest struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Address  string `json:"address"`
+	TTL      string `json:"ttl"`
+	Priority string `json:"priority"`
+	Proxied  bool   `json:"proxied"`
+}
+
+// Add the API routes used to manage domains
+func RouteAPI(e *core.ServeEvent) error {
+	g := e.Router.Group("/api/redcompass")
+	g.Bind(apis.RequireAuth("users"))
+
+	// DNS record management, changes are pushed to the provider and then saved to Domain_Records
+	g.POST("/domains/{id}/records", createDomainRecordHandler)
+	g.PATCH("/records/{id}", updateDomainRecordHandler)
+	g.DELETE("/records/{id}", deleteDomainRecordHandler)
+
+	// On-demand jobs, these run the same code as the cron jobs and share the per-service lock
+	g.POST("/services/{id}/sync", syncServiceHandler)
+	g.POST("/services/{id}/scan", scanServiceHandler)
+	g.POST("/services/{id}/check", checkServiceHandler)
+	g.POST("/domains/{id}/scan", scanDomainHandler)
+
+	// VirusTotal scan history of a domain, used for the reputation charts on the domain page
+	g.GET("/domains/{id}/reputation", domainReputationHandler)
+
+	// Email authentication checks of a domain, run now instead of waiting for the daily check
+	g.POST("/domains/{id}/email-auth", emailAuthDomainHandler)
+
+	// Vendor categorisation tracking, recategorisation requests are logged with the user that submitted them
+	g.POST("/domains/{id}/categorizations/submissions", categorizationSubmissionHandler)
+	g.GET("/categorizations/uncategorized", uncategorizedDomainsHandler)
+
+	// Registered providers and the JSON schema of their settings, used by the Settings page
+	g.GET("/providers", providersHandler)
+
+	// Request metrics for the services and scanners since the app started
+	g.GET("/metrics/providers", providerMetricsHandler)
+
+	return e.Next()
+}
+
+// Get the registered registrars, scanners and checkers
+func providersHandler(e *core.RequestEvent) error {
+	return e.JSON(http.StatusOK, providers.List(""))
+}
+
+// Get the request metrics for each provider, only admins can view them
+func providerMetricsHandler(e *core.RequestEvent) error {
+	if e.Auth.GetString("role") != "admin" {
+		return e.ForbiddenError("Only admins can view the provider metrics", nil)
+	}
+
+	return e.JSON(http.StatusOK, providers.GetMetrics())
+}
+
+// Create a new DNS record for a domain
+func createDomainRecordHandler(e *core.RequestEvent) error {
+	domain, err := e.App.FindRecordById("Domains", e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("Domain not found", err)
+	}
+
+	if err := canUpdateDomain(e, domain); err != nil {
+		return err
+	}
+
+	body, err := bindDomainRecordRequest(e)
+	if err != nil {
+		return err
+	}
+
+	service, err := getDomainService(domain)
+	if err != nil {
+		return e.BadRequestError(err.Error(), err)
+	}
+
+	r, err := service.CreateRecord(e.Request.Context(), types.Record{
+		Domain:   domain.GetString("Name"),
+		Name:     body.Name,
+		Type:     body.Type,
+		Address:  body.Address,
+		TTL:      body.TTL,
+		Priority: body.Priority,
+		Proxied:  body.Proxied,
+	})
+	if err != nil {
+		e.App.Logger().Error("RECORDS:"+domain.GetString("Name")+" create record", "function", "service.CreateRecord", "error", err.Error())
+		return providerError(e, "Failed to create the record with the provider", err)
+	}
+
+	recordsCollection, err := e.App.FindCollectionByNameOrId("Domain_Records")
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	record := core.NewRecord(recordsCollection)
+	record.Set("Domain", domain.Id)
+	setDomainRecordFields(record, r)
+	if err := e.App.Save(record); err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	e.App.Logger().Info("RECORDS:"+domain.GetString("Name")+" create record", "status", "created", "recordName", r.Name, "recordType", r.Type, "userID", e.Auth.Id)
+	refreshDomainRecords(e.Request.Context(), service, domain)
+	return e.JSON(http.StatusOK, record)
+}
+
+// Update an existing DNS record
+func updateDomainRecordHandler(e *core.RequestEvent) error {
+	record, domain, err := findDomainRecord(e)
+	if err != nil {
+		return err
+	}
+
+	body, err := bindDomainRecordRequest(e)
+	if err != nil {
+		return err
+	}
+
+	service, err := getDomainService(domain)
+	if err != nil {
+		return e.BadRequestError(err.Error(), err)
+	}
+
+	r, err := service.UpdateRecord(e.Request.Context(), types.Record{
+		Domain:   domain.GetString("Name"),
+		HostId:   record.GetString("Host_Id"),
+		Name:     body.Name,
+		Type:     body.Type,
+		Address:  body.Address,
+		TTL:      body.TTL,
+		Priority: body.Priority,
+		Proxied:  body.Proxied,
+	})
+	if err != nil {
+		e.App.Logger().Error("RECORDS:"+domain.GetString("Name")+" update record", "function", "service.UpdateRecord", "error", err.Error())
+		return providerError(e, "Failed to update the record with the provider", err)
+	}
+
+	setDomainRecordFields(record, r)
+	if err := e.App.Save(record); err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	e.App.Logger().Info("RECORDS:"+domain.GetString("Name")+" update record", "status", "updated", "recordName", r.Name, "recordType", r.Type, "userID", e.Auth.Id)
+	refreshDomainRecords(e.Request.Context(), service, domain)
+	return e.JSON(http.StatusOK, record)
+}
+
+// Delete an existing DNS record
+func deleteDomainRecordHandler(e *core.RequestEvent) error {
+	record, domain, err := findDomainRecord(e)
+	if err != nil {
+		return err
+	}
+
+	service, err := getDomainService(domain)
+	if err != nil {
+		return e.BadRequestError(err.Error(), err)
+	}
+
+	r := domainRecordFromRecord(domain.GetString("Name"), record)
+	if err := service.DeleteRecord(e.Request.Context(), r); err != nil {
+		e.App.Logger().Error("RECORDS:"+domain.GetString("Name")+" delete record", "function", "service.DeleteRecord", "error", err.Error())
+		return providerError(e, "Failed to delete the record with the provider", err)
+	}
+
+	if err := e.App.Delete(record); err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	e.App.Logger().Info("RECORDS:"+domain.GetString("Name")+" delete record", "status", "deleted", "recordName", r.Name, "recordType", r.Type, "userID", e.Auth.Id)
+	refreshDomainRecords(e.Request.Context(), service, domain)
+	return e.NoContent(http.StatusNoContent)
+}
+
+// Run a domain sync for a registrar service now
+func syncServiceHandler(e *core.RequestEvent) error {
+	service, err := findService(e)
+	if err != nil {
+		return err
+	}
+
+	if !isProviderKind(service, providers.KindRegistrar) {
+		return e.BadRequestError("The service is not a registrar", nil)
+	}
+
+	return startServiceJob(e, service, func() {
+		SyncServiceDomains(jobsCtx, service, TriggerManual)
+	})
+}
+
+// Run a scan of all domains for a scanner service now
+func scanServiceHandler(e *core.RequestEvent) error {
+	service, err := findService(e)
+	if err != nil {
+		return err
+	}
+
+	if !isProviderKind(service, providers.KindScanner) {
+		return e.BadRequestError("The service is not a scanner", nil)
+	}
+
+	return startServiceJob(e, service, func() {
+		ScanDomains(jobsCtx, service, TriggerManual, "")
+	})
+}
+
+// Run a categorisation check of all domains for a checker service now
+func checkServiceHandler(e *core.RequestEvent) error {
+	service, err := findService(e)
+	if err != nil {
+		return err
+	}
+
+	if !isProviderKind(service, providers.KindChecker) {
+		return e.BadRequestError("The service is not a categorization checker", nil)
+	}
+
+	return startServiceJob(e, service, func() {
+		CheckCategories(jobsCtx, service, TriggerManual)
+	})
+}
+
+// Scan a single domain now. The service query parameter sets the scanner service to use, only
+// users that can view services can set it. The oldest enabled scanner service is used otherwise.
+func scanDomainHandler(e *core.RequestEvent) error {
+	domain, err := e.App.FindRecordById("Domains", e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("Domain not found", err)
+	}
+
+	info, err := e.RequestInfo()
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	canAccess, err := e.App.CanAccessRecord(domain, info, domain.Collection().UpdateRule)
+	if !canAccess {
+		return e.ForbiddenError("You are not allowed to scan this domain", err)
+	}
+
+	if domain.GetBool("Is_Expired") || domain.GetBool("Is_Locked") {
+		return e.BadRequestError("Expired and locked domains are not scanned", nil)
+	}
+
+	if domain.GetBool("Removed_From_Provider") {
+		return e.BadRequestError("Domains removed from the provider are not scanned", nil)
+	}
+
+	var service *core.Record
+	if serviceID := e.Request.URL.Query().Get("service"); serviceID != "" {
+		service, err = e.App.FindRecordById("Services", serviceID)
+		if err != nil {
+			return e.NotFoundError("Service not found", err)
+		}
+
+		canAccess, err := e.App.CanAccessRecord(service, info, service.Collection().ViewRule)
+		if !canAccess {
+			return e.ForbiddenError("You are not allowed to use this service", err)
+		}
+
+		if !isProviderKind(service, providers.KindScanner) {
+			return e.BadRequestError("The service is not a scanner", nil)
+		}
+	} else {
+		service, err = findScanService(e.App)
+		if err != nil {
+			return e.NotFoundError("No enabled scanner service is configured", err)
+		}
+	}
+
+	return startServiceJob(e, service, func() {
+		ScanDomains(jobsCtx, service, TriggerManual, domain.Id)
+	})
+}
+
+// Check the SPF, DMARC, MX and DKIM records of a domain now and return the results
+func emailAuthDomainHandler(e *core.RequestEvent) error {
+	domain, err := e.App.FindRecordById("Domains", e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("Domain not found", err)
+	}
+
+	info, err := e.RequestInfo()
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	canAccess, err := e.App.CanAccessRecord(domain, info, domain.Collection().UpdateRule)
+	if !canAccess {
+		return e.ForbiddenError("You are not allowed to check this domain", err)
+	}
+
+	results, err := CheckDomainEmailAuth(e.Request.Context(), domain)
+	if err != nil {
+		return e.BadRequestError("Failed to check the email records", err)
+	}
+
+	return e.JSON(http.StatusOK, results)
+}
+
+// Scan of a domain in the reputation trend
+type ReputationScan struct {
+	Scanner    string                        `json:"scanner"`
+	Scanned    pbtypes.DateTime              `json:"scanned"`
+	Malicious  int                           `json:"malicious"`
+	Suspicious int                           `json:"suspicious"`
+	Undetected int                           `json:"undetected"`
+	Harmless   int                           `json:"harmless"`
+	Timeout    int                           `json:"timeout"`
+	Changes    []virustotal.VT_Engine_Change `json:"changes"`
+}
+
+// Get the reputation trend of a domain, the last scans oldest first. The number of scans
+// defaults to 90 and can be set with the limit query parameter up to 365. The scanner query
+// parameter limits the trend to the scans of one scanner.
+func domainReputationHandler(e *core.RequestEvent) error {
+	domain, err := e.App.FindRecordById("Domains", e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("Domain not found", err)
+	}
+
+	info, err := e.RequestInfo()
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	canAccess, err := e.App.CanAccessRecord(domain, info, domain.Collection().ViewRule)
+	if !canAccess {
+		return e.ForbiddenError("You are not allowed to view this domain", err)
+	}
+
+	limit := 90
+	if l, err := strconv.Atoi(e.Request.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, 365)
+	}
+
+	filter := "Domain = {:domain}"
+	scanner := e.Request.URL.Query().Get("scanner")
+	if scanner != "" {
+		filter += " && Scanner = {:scanner}"
+	}
+
+	records, err := e.App.FindRecordsByFilter("VirusTotal_Scans", filter, "-Scanned", limit, 0,
+		dbx.Params{"domain": domain.GetString("Name"), "scanner": scanner},
+	)
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	scans := make([]ReputationScan, len(records))
+	for i, record := range records {
+		scan := ReputationScan{
+			Scanner:    record.GetString("Scanner"),
+			Scanned:    record.GetDateTime("Scanned"),
+			Malicious:  record.GetInt("Malicious"),
+			Suspicious: record.GetInt("Suspicious"),
+			Undetected: record.GetInt("Undetected"),
+			Harmless:   record.GetInt("Harmless"),
+			Timeout:    record.GetInt("Timeout"),
+			Changes:    []virustotal.VT_Engine_Change{},
+		}
+		if err := record.UnmarshalJSONField("Changes", &scan.Changes); err != nil {
+			return e.InternalServerError("", err)
+		}
+		if scan.Changes == nil {
+			scan.Changes = []virustotal.VT_Engine_Change{}
+		}
+
+		// Oldest first for the charts
+		scans[len(records)-1-i] = scan
+	}
+
+	return e.JSON(http.StatusOK, map[string]any{
+		"domain": domain.GetString("Name"),
+		"scans":  scans,
+	})
+}
+
+// Body of a request to log a recategorisation request
+type CategorizationSubmissionRequest struct {
+	Vendor   string `json:"vendor"`
+	Category string `json:"category"`
+}
+
+// Log that a recategorisation of a domain was requested at a vendor
+func categorizationSubmissionHandler(e *core.RequestEvent) error {
+	domain, err := e.App.FindRecordById("Domains", e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("Domain not found", err)
+	}
+
+	if err := canUpdateDomain(e, domain); err != nil {
+		return err
+	}
+
+	var body CategorizationSubmissionRequest
+	if err := e.BindBody(&body); err != nil {
+		return e.BadRequestError("Invalid request body", err)
+	}
+	body.Vendor = strings.TrimSpace(body.Vendor)
+	body.Category = strings.TrimSpace(body.Category)
+	if body.Vendor == "" || body.Category == "" {
+		return e.BadRequestError("The vendor and the requested category are required", nil)
+	}
+
+	user := e.Auth.GetString("name")
+	if user == "" {
+		user = e.Auth.Email()
+	}
+
+	record, err := AddCategorizationSubmission(domain.Id, body.Vendor, categorization.Submission{
+		User:     user,
+		Date:     time.Now().UTC(),
+		Category: body.Category,
+	})
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	e.App.Logger().Info("CATEGORIZATION:"+domain.GetString("Name")+" submission", "vendor", record.GetString("Vendor"), "category", body.Category, "userID", e.Auth.Id)
+	return e.JSON(http.StatusOK, record)
+}
+
+// Category of a domain at a vendor in the uncategorised domains
+type UncategorizedVendor struct {
+	Vendor        string           `json:"vendor"`
+	Category      string           `json:"category"`
+	LastChecked   pbtypes.DateTime `json:"lastChecked"`
+	LastSubmitted pbtypes.DateTime `json:"lastSubmitted"`
+}
+
+// Domain with the vendors that have not categorised it
+type UncategorizedDomain struct {
+	ID               string                `json:"id"`
+	Name             string                `json:"name"`
+	AssignedProject  string                `json:"assignedProject"`
+	UncategorizedAt  []UncategorizedVendor `json:"uncategorizedAt"`
+	CategorizedCount int                   `json:"categorizedCount"`
+}
+
+// Get the domains that are still uncategorised at any of the tracked vendors. Expired domains
+// and domains removed from the provider are left out. The project query parameter limits the
+// domains to a project and the vendors parameter, a comma separated list, replaces the tracked vendors.
+func uncategorizedDomainsHandler(e *core.RequestEvent) error {
+	query := e.Request.URL.Query()
+
+	vendors := categorization.Vendors
+	if v := query.Get("vendors"); v != "" {
+		vendors = nil
+		for _, vendor := range strings.Split(v, ",") {
+			if vendor = strings.TrimSpace(vendor); vendor != "" {
+				vendors = append(vendors, categorization.Normalize(vendor))
+			}
+		}
+	}
+
+	filters := []dbx.Expression{
+		dbx.HashExp{"Is_Expired": false, "Removed_From_Provider": false},
+	}
+	if project := query.Get("project"); project != "" {
+		filters = append(filters, dbx.HashExp{"Assigned_Project": project})
+	}
+	domains, err := e.App.FindAllRecords("Domains", filters...)
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	// Categories of the domains by domain id and vendor
+	ids := make([]any, len(domains))
+	for i, d := range domains {
+		ids[i] = d.Id
+	}
+	categories := map[string]map[string]*core.Record{}
+	if len(ids) > 0 {
+		records, err := e.App.FindAllRecords("Categorizations", dbx.In("Domain", ids...))
+		if err != nil {
+			return e.InternalServerError("", err)
+		}
+		for _, r := range records {
+			if categories[r.GetString("Domain")] == nil {
+				categories[r.GetString("Domain")] = map[string]*core.Record{}
+			}
+			categories[r.GetString("Domain")][r.GetString("Vendor")] = r
+		}
+	}
+
+	result := []UncategorizedDomain{}
+	for _, d := range domains {
+		domain := UncategorizedDomain{
+			ID:              d.Id,
+			Name:            d.GetString("Name"),
+			AssignedProject: d.GetString("Assigned_Project"),
+			UncategorizedAt: []UncategorizedVendor{},
+		}
+
+		for _, vendor := range vendors {
+			r := categories[d.Id][vendor]
+			if r != nil && !categorization.IsUncategorized(r.GetString("Category")) {
+				domain.CategorizedCount++
+				continue
+			}
+
+			entry := UncategorizedVendor{Vendor: vendor}
+			if r != nil {
+				entry.Category = r.GetString("Category")
+				entry.LastChecked = r.GetDateTime("Last_Checked")
+				entry.LastSubmitted = r.GetDateTime("Last_Submitted")
+			}
+			domain.UncategorizedAt = append(domain.UncategorizedAt, entry)
+		}
+
+		if len(domain.UncategorizedAt) > 0 {
+			result = append(result, domain)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b UncategorizedDomain) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return e.JSON(http.StatusOK, result)
+}
+
+// Check if the provider of a service is registered with the kind
+func isProviderKind(service *core.Record, kind providers.Kind) bool {
+	provider, ok := providers.Lookup(service.GetString("Provider"))
+	return ok && provider.Kind == kind
+}
+
+// Find the service used to scan a single domain when none is chosen, the oldest enabled service
+// of a registered scanner so the same account is used every time
+func findScanService(app core.App) (*core.Record, error) {
+	names := []any{}
+	for _, name := range providers.Names(providers.KindScanner) {
+		names = append(names, name)
+	}
+
+	service := &core.Record{}
+	err := app.RecordQuery("Services").
+		AndWhere(dbx.HashExp{"Disabled": false}).
+		AndWhere(dbx.In("Provider", names...)).
+		OrderBy("created ASC", "id ASC").
+		Limit(1).
+		One(service)
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// Find the service from the path, only users that can view services can run their jobs
+func findService(e *core.RequestEvent) (*core.Record, error) {
+	service, err := e.App.FindRecordById("Services", e.Request.PathValue("id"))
+	if err != nil {
+		return nil, e.NotFoundError("Service not found", err)
+	}
+
+	info, err := e.RequestInfo()
+	if err != nil {
+		return nil, e.InternalServerError("", err)
+	}
+
+	canAccess, err := e.App.CanAccessRecord(service, info, service.Collection().ViewRule)
+	if !canAccess {
+		return nil, e.ForbiddenError("You are not allowed to run jobs for this service", err)
+	}
+
+	return service, nil
+}
+
+// Run a job for a service in the background while holding the service lock. A conflict is
+// returned if a scheduled or manual run for the service is already in progress.
+func startServiceJob(e *core.RequestEvent, service *core.Record, job func()) error {
+	if service.GetBool("Disabled") {
+		return e.BadRequestError("The service is disabled: "+service.GetString("Disabled_Reason"), nil)
+	}
+
+	unlock, ok := lockService(service.Id)
+	if !ok {
+		return e.Error(http.StatusConflict, "A job for this service is already running", nil)
+	}
+
+	e.App.Logger().Info("JOBS:"+serviceLogName(service)+" manual run", "status", "started", "userID", e.Auth.Id)
+	go func() {
+		defer unlock()
+		job()
+	}()
+
+	return e.JSON(http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// Return an API error that matches the kind of error the provider returned
+func providerError(e *core.RequestEvent, message string, err error) error {
+	switch {
+	case errors.Is(err, providers.ErrRateLimited):
+		return e.TooManyRequestsError(message+", the provider is rate limiting requests", err)
+	case errors.Is(err, providers.ErrNotFound):
+		return e.NotFoundError(message+", it was not found", err)
+	case errors.Is(err, providers.ErrAuth):
+		return e.BadRequestError(message+", the provider rejected the service credentials", err)
+	}
+	return e.BadRequestError(message, err)
+}
+
+// Sync the domain's records after a change since some providers, like Namecheap, assign a new
+// HostId to every record. The change itself was already saved so it is not reported as drift.
+func refreshDomainRecords(ctx context.Context, service types.Service, domain *core.Record) {
+	msg := "RECORDS:" + domain.GetString("Name") + " refresh records"
+
+	records, err := service.GetDomainRecords(ctx, domain.GetString("Name"))
+	if err != nil {
+		app.Logger().Error(msg, "function", "service.GetDomainRecords", "error", err.Error())
+		return
+	}
+
+	_, err = SyncDomainRecords(service.GetName(), domain.GetString("Name"), records)
+	if err != nil {
+		app.Logger().Error(msg, "function", "SyncDomainRecords", "error", err.Error())
+	}
+}
+
+// Find the Domain_Records record from the path and its domain
+func findDomainRecord(e *core.RequestEvent) (*core.Record, *core.Record, error) {
+	record, err := e.App.FindRecordById("Domain_Records", e.Request.PathValue("id"))
+	if err != nil {
+		return nil, nil, e.NotFoundError("Record not found", err)
+	}
+
+	domain, err := e.App.FindRecordById("Domains", record.GetString("Domain"))
+	if err != nil {
+		return nil, nil, e.NotFoundError("Domain not found", err)
+	}
+
+	if err := canUpdateDomain(e, domain); err != nil {
+		return nil, nil, err
+	}
+
+	return record, domain, nil
+}
+
+// Check the user is allowed to update the domain using the Domains update rule
+func canUpdateDomain(e *core.RequestEvent, domain *core.Record) error {
+	info, err := e.RequestInfo()
+	if err != nil {
+		return e.InternalServerError("", err)
+	}
+
+	canAccess, err := e.App.CanAccessRecord(domain, info, domain.Collection().UpdateRule)
+	if !canAccess {
+		return e.ForbiddenError("You are not allowed to update the records for this domain", err)
+	}
+
+	if domain.GetBool("Custom_DNS") {
+		return e.BadRequestError("The domain is not using the provider's DNS", nil)
+	}
+
+	return nil
+}
+
+// Validate the body of a create or update record request
+func bindDomainRecordRequest(e *core.RequestEvent) (DomainRecordRequest, error) {
+	var body DomainRecordRequest
+	if err := e.BindBody(&body); err != nil {
+		return body, e.BadRequestError("Invalid request body", err)
+	}
+
+	if body.Type == "" || body.Address == "" {
+		return body, e.BadRequestError("The record type and address are required", nil)
+	}
+
+	return body, nil
+}
+
+// Get the service client for the account the domain was synced from. Domains that have not
+// been synced since services were linked fall back to the first service for the provider.
+func getDomainService(domain *core.Record) (types.Service, error) {
+	if domain.GetBool("Removed_From_Provider") {
+		return nil, errors.New("domain has been removed from " + domain.GetString("Domain_Provider"))
+	}
+
+	var serviceRecord *core.Record
+	var err error
+	if domain.GetString("Service") != "" {
+		serviceRecord, err = app.FindRecordById("Services", domain.GetString("Service"))
+	} else {
+		serviceRecord, err = app.FindFirstRecordByData("Services", "Provider", domain.GetString("Domain_Provider"))
+	}
+	if err != nil {
+		return nil, errors.New("no service settings found for " + domain.GetString("Domain_Provider"))
+	}
+
+	settings, err := GetServiceSettings(serviceRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.NewService(serviceRecord.GetString("Provider"), settings)
+}
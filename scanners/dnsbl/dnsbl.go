@@ -0,0 +1,284 @@
+// Package dnsbl checks domains against DNS blocklists. Domain lists (RHSBL) such as the Spamhaus
+// DBL, SURBL and URIBL are queried with the domain, IP lists (DNSBL) such as Spamhaus ZEN with
+// the addresses of the domain and its mail servers.
+package dnsbl
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/scanners/types"
+)
+
+// Name of the scanner saved as the Provider of the Services record
+const Name = "DNSBL"
+
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     Name,
+		Kind:     providers.KindScanner,
+		Settings: settingsSchema,
+		NewScanner: func(settings string) (types.Scanner, error) {
+			return NewClient(settings)
+		},
+		Store: Store{},
+	})
+}
+
+type Client struct {
+	resolver    *net.Resolver
+	domainLists []string
+	ipLists     []string
+}
+
+type dnsbl_settings struct {
+	Resolver    string `json:"resolver"`
+	DomainLists string `json:"domainLists"`
+	IPLists     string `json:"ipLists"`
+}
+
+// Results of the lists for a domain, returned as JSON by GetResults
+type Results struct {
+	Domain string `json:"domain"`
+
+	// A and MX addresses of the domain that were looked up on the IP lists
+	Addresses []string `json:"addresses"`
+
+	Listings []Listing `json:"listings"`
+}
+
+// Result of a list, the entries are the domain or addresses that are listed
+type Listing struct {
+	List    string  `json:"list"`
+	Type    string  `json:"type"`
+	Listed  bool    `json:"listed"`
+	Entries []Entry `json:"entries"`
+
+	// Set when the list could not be queried, e.g. it refused the resolver
+	Error string `json:"error,omitempty"`
+}
+
+// Domain or address found on a list with the return code and its meaning. Informational
+// entries describe the address without saying anything about its reputation, so they do not
+// count as a listing.
+type Entry struct {
+	Name          string `json:"name"`
+	Code          string `json:"code"`
+	Reason        string `json:"reason"`
+	Informational bool   `json:"informational,omitempty"`
+}
+
+func NewClient(settings string) (*Client, error) {
+	var dnsblSettings dnsbl_settings
+	if settings != "" {
+		if err := json.Unmarshal([]byte(settings), &dnsblSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Client{
+		resolver:    &net.Resolver{},
+		domainLists: splitLists(dnsblSettings.DomainLists, DefaultDomainLists),
+		ipLists:     splitLists(dnsblSettings.IPLists, DefaultIPLists),
+	}
+
+	// Send every query to the configured resolver instead of the system one
+	if dnsblSettings.Resolver != "" {
+		address := dnsblSettings.Resolver
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, "53")
+		}
+		c.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, address)
+			},
+		}
+	}
+
+	return c, nil
+}
+
+// Split a comma separated list of zones, the defaults are used when it is empty
+func splitLists(value string, defaults []string) []string {
+	var lists []string
+	for _, zone := range strings.Split(value, ",") {
+		zone = strings.Trim(strings.ToLower(strings.TrimSpace(zone)), ".")
+		if zone != "" {
+			lists = append(lists, zone)
+		}
+	}
+	if len(lists) == 0 {
+		return defaults
+	}
+	return lists
+}
+
+// GetName returns the name of the scanner
+func (c *Client) GetName() string {
+	return Name
+}
+
+// ValidateKey checks the resolver can query every list using the test entries every list has
+// (RFC 5782), lists that refuse the resolver return an authentication error
+func (c *Client) ValidateKey(ctx context.Context) error {
+	for _, zone := range c.domainLists {
+		if _, err := c.lookup(ctx, zone, "test."+zone+"."); err != nil {
+			return err
+		}
+	}
+	for _, zone := range c.ipLists {
+		if _, err := c.lookup(ctx, zone, "2.0.0.127."+zone+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDailyQuotaRemaining returns the lookups remaining today, the lists do not have a quota
+// that can be checked so every domain is scanned
+func (c *Client) GetDailyQuotaRemaining(ctx context.Context) (int, error) {
+	return math.MaxInt32, nil
+}
+
+// GetResults queries the lists for a domain. A list that fails is saved with the error, the
+// scan only fails when no list could be queried.
+func (c *Client) GetResults(ctx context.Context, domain string) ([]byte, error) {
+	addresses, err := c.addresses(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	results := Results{Domain: domain, Addresses: addresses, Listings: []Listing{}}
+	var firstErr error
+	failed := 0
+
+	check := func(zone string, listType string, names []string) {
+		listing := Listing{List: zone, Type: listType, Entries: []Entry{}}
+		for _, name := range names {
+			entries, err := c.lookup(ctx, zone, queryName(zone, listType, name))
+			if err != nil {
+				listing.Error = err.Error()
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				break
+			}
+			for _, entry := range entries {
+				entry.Name = name
+				listing.Entries = append(listing.Entries, entry)
+			}
+		}
+		listing.Listed = slices.ContainsFunc(listing.Entries, func(e Entry) bool { return !e.Informational })
+		results.Listings = append(results.Listings, listing)
+	}
+
+	for _, zone := range c.domainLists {
+		check(zone, ListDomain, []string{domain})
+	}
+	for _, zone := range c.ipLists {
+		check(zone, ListIP, addresses)
+	}
+
+	if failed > 0 && failed == len(results.Listings) {
+		return nil, firstErr
+	}
+	return json.Marshal(results)
+}
+
+// IPv4 addresses of the domain and its mail servers, a domain without records has none
+func (c *Client) addresses(ctx context.Context, domain string) ([]string, error) {
+	hosts := []string{domain + "."}
+
+	mx, err := c.resolver.LookupMX(ctx, domain+".")
+	if err != nil && !isNotFound(err) {
+		return nil, dnsError(err)
+	}
+	for _, record := range mx {
+		if record.Host != "." {
+			hosts = append(hosts, record.Host)
+		}
+	}
+
+	var addresses []string
+	for _, host := range hosts {
+		ips, err := c.resolver.LookupIP(ctx, "ip4", host)
+		if err != nil && !isNotFound(err) {
+			return nil, dnsError(err)
+		}
+		for _, ip := range ips {
+			if address := ip.String(); !slices.Contains(addresses, address) {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// Look up a name on a list, no entries are returned when it is not listed
+func (c *Client) lookup(ctx context.Context, zone string, name string) ([]Entry, error) {
+	ips, err := c.resolver.LookupIP(ctx, "ip4", name)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dnsError(err)
+	}
+
+	var entries []Entry
+	for _, ip := range ips {
+		entry, err := decode(zone, ip)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// Classify a failed lookup, timeouts and server failures are transient
+func dnsError(err error) error {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && (dnsErr.IsTimeout || dnsErr.IsTemporary) {
+		return &providers.Error{Provider: Name, Kind: providers.ErrTransient, Err: err}
+	}
+	return providers.FromRequestError(Name, err)
+}
+
+// Reasons of the listing, the addresses are included for IP lists, e.g. "192.0.2.25: SBL spam source".
+// Informational entries are skipped.
+func (l Listing) Reasons() []string {
+	var reasons []string
+	for _, entry := range l.Entries {
+		if entry.Informational {
+			continue
+		}
+		reason := entry.Reason
+		if l.Type == ListIP {
+			reason = entry.Name + ": " + reason
+		}
+		if !slices.Contains(reasons, reason) {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,161 @@
+package dnsbl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/providers/providertest"
+)
+
+// Start a fake DNS server and create a client that queries the default lists through it
+func startClient(t *testing.T) (*providertest.DNS, *Client) {
+	dns := providertest.NewDNS()
+	resolver := providertest.StartDNS(t, dns)
+
+	c, err := NewClient(`{"resolver":"` + resolver + `"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dns, c
+}
+
+func TestResults(t *testing.T) {
+	dns, c := startClient(t)
+	dns.SetA("burned.com", "192.0.2.1")
+	dns.SetMX("burned.com", "mail.burned.com")
+	dns.SetA("mail.burned.com", "192.0.2.25")
+	dns.ListDomain("dbl.spamhaus.org", "burned.com", "127.0.1.4")
+	dns.ListDomain("multi.surbl.org", "burned.com", "127.0.0.24")
+	dns.ListIP("zen.spamhaus.org", "192.0.2.25", "127.0.0.2")
+
+	b, err := c.GetResults(context.Background(), "burned.com")
+	if err != nil {
+		t.Fatalf("GetResults: %v", err)
+	}
+	var results Results
+	if err := json.Unmarshal(b, &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results.Addresses) != 2 {
+		t.Fatalf("expected the A and MX addresses, got %v", results.Addresses)
+	}
+
+	want := map[string]string{
+		"dbl.spamhaus.org": "phishing domain",
+		"multi.surbl.org":  "phishing, malware",
+		"zen.spamhaus.org": "SBL spam source",
+	}
+	for _, listing := range results.Listings {
+		if listing.Error != "" {
+			t.Errorf("%s: unexpected error %s", listing.List, listing.Error)
+		}
+		reason, listed := want[listing.List]
+		if listing.Listed != listed {
+			t.Errorf("%s: expected listed %t, got %t", listing.List, listed, listing.Listed)
+			continue
+		}
+		if listed && listing.Entries[0].Reason != reason {
+			t.Errorf("%s: expected reason %q, got %q", listing.List, reason, listing.Entries[0].Reason)
+		}
+	}
+	if len(results.Listings) != len(DefaultDomainLists)+len(DefaultIPLists) {
+		t.Fatalf("expected a listing per list, got %d", len(results.Listings))
+	}
+}
+
+func TestPolicyBlocklist(t *testing.T) {
+	dns, c := startClient(t)
+	dns.SetA("home.com", "192.0.2.1")
+	dns.SetA("mixed.com", "192.0.2.2")
+	dns.ListIP("zen.spamhaus.org", "192.0.2.1", "127.0.0.11")
+	dns.ListIP("zen.spamhaus.org", "192.0.2.2", "127.0.0.10", "127.0.0.4")
+
+	tests := []struct {
+		domain  string
+		listed  bool
+		reasons []string
+	}{
+		{"home.com", false, nil},
+		{"mixed.com", true, []string{"192.0.2.2: XBL exploited host"}},
+	}
+	for _, tt := range tests {
+		b, err := c.GetResults(context.Background(), tt.domain)
+		if err != nil {
+			t.Fatalf("GetResults: %v", err)
+		}
+		var results Results
+		if err := json.Unmarshal(b, &results); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, listing := range results.Listings {
+			if listing.List != "zen.spamhaus.org" {
+				continue
+			}
+			if listing.Listed != tt.listed {
+				t.Errorf("%s: expected listed %t, got %t", tt.domain, tt.listed, listing.Listed)
+			}
+			if !slices.Equal(listing.Reasons(), tt.reasons) {
+				t.Errorf("%s: expected reasons %v, got %v", tt.domain, tt.reasons, listing.Reasons())
+			}
+		}
+	}
+}
+
+func TestRefusedResolver(t *testing.T) {
+	dns, c := startClient(t)
+	dns.SetA("test.dbl.spamhaus.org", "127.255.255.254")
+
+	err := c.ValidateKey(context.Background())
+	if !errors.Is(err, providers.ErrAuth) {
+		t.Fatalf("expected an authentication error, got %v", err)
+	}
+}
+
+func TestServerFailure(t *testing.T) {
+	dns, c := startClient(t)
+	dns.Fail(providertest.FailUnavailable, -1)
+
+	_, err := c.GetResults(context.Background(), "example.com")
+	if !errors.Is(err, providers.ErrTransient) {
+		t.Fatalf("expected a transient error, got %v", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		zone          string
+		code          string
+		want          string
+		informational bool
+	}{
+		{"dbl.spamhaus.org", "127.0.1.2", "spam domain", false},
+		{"multi.uribl.com", "127.0.0.6", "black, grey", false},
+		{"zen.spamhaus.org", "127.0.0.2", "SBL spam source", false},
+		{"zen.spamhaus.org", "127.0.0.10", "PBL ISP maintained dynamic range", true},
+		{"zen.spamhaus.org", "127.0.0.11", "PBL Spamhaus maintained dynamic range", true},
+		{"dnsbl.example.org", "127.0.0.5", "listed (127.0.0.5)", false},
+	}
+	for _, tt := range tests {
+		got, err := decode(tt.zone, net.ParseIP(tt.code))
+		if err != nil {
+			t.Fatalf("decode(%s, %s): %v", tt.zone, tt.code, err)
+		}
+		if got.Reason != tt.want || got.Informational != tt.informational {
+			t.Errorf("decode(%s, %s) = %q (informational %t), want %q (informational %t)", tt.zone, tt.code, got.Reason, got.Informational, tt.want, tt.informational)
+		}
+	}
+
+	if _, err := decode("dbl.spamhaus.org", net.ParseIP("127.255.255.255")); !errors.Is(err, providers.ErrRateLimited) {
+		t.Errorf("expected a rate limited error, got %v", err)
+	}
+	if _, err := decode("multi.surbl.org", net.ParseIP("192.0.2.1")); err == nil {
+		t.Error("expected an error for an address outside 127.0.0.0/8")
+	}
+}
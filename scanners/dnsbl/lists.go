@@ -0,0 +1,147 @@
+package dnsbl
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/lum8rjack/redcompass/providers"
+)
+
+// Kinds of lists, domain lists (RHSBL) are queried with the domain and IP lists (DNSBL) with
+// the addresses the domain and its mail servers resolve to
+const (
+	ListDomain = "domain"
+	ListIP     = "ip"
+)
+
+// Lists queried when the settings do not set any
+var (
+	DefaultDomainLists = []string{"dbl.spamhaus.org", "multi.surbl.org", "multi.uribl.com"}
+	DefaultIPLists     = []string{"zen.spamhaus.org", "bl.spamcop.net", "b.barracudacentral.org"}
+)
+
+// Return codes of a list. Most lists return a code per listing reason, SURBL and URIBL return a
+// bitmask in the last octet. Some codes are not listings but errors, e.g. Spamhaus refuses
+// queries coming from public resolvers, and some only describe the address, e.g. the Spamhaus
+// PBL lists dynamic and ISP ranges that should not send mail directly.
+type decoder struct {
+	codes  map[string]string
+	bits   map[byte]string
+	errors map[string]error
+	info   map[string]string
+}
+
+// Errors returned by the Spamhaus lists instead of a listing
+var spamhausErrors = map[string]error{
+	"127.255.255.252": providers.NewError("Spamhaus", providers.ErrAuth, "the query is malformed"),
+	"127.255.255.254": providers.NewError("Spamhaus", providers.ErrAuth, "queries through public or open resolvers are refused, use your own resolver"),
+	"127.255.255.255": providers.RateLimited("Spamhaus", 0, "too many queries from the resolver"),
+}
+
+// Return codes of the well known lists, any 127.0.0.0/8 answer of another list is a listing
+var decoders = map[string]decoder{
+	"dbl.spamhaus.org": {
+		codes: map[string]string{
+			"127.0.1.2":   "spam domain",
+			"127.0.1.4":   "phishing domain",
+			"127.0.1.5":   "malware domain",
+			"127.0.1.6":   "botnet C&C domain",
+			"127.0.1.102": "abused legit spam",
+			"127.0.1.103": "abused spammed redirector",
+			"127.0.1.104": "abused legit phishing",
+			"127.0.1.105": "abused legit malware",
+			"127.0.1.106": "abused legit botnet C&C",
+		},
+		errors: spamhausErrors,
+	},
+	"zen.spamhaus.org": {
+		codes: map[string]string{
+			"127.0.0.2": "SBL spam source",
+			"127.0.0.3": "SBL CSS spam source",
+			"127.0.0.4": "XBL exploited host",
+			"127.0.0.5": "XBL exploited host",
+			"127.0.0.6": "XBL exploited host",
+			"127.0.0.7": "XBL exploited host",
+			"127.0.0.9": "DROP hijacked network",
+		},
+		info: map[string]string{
+			"127.0.0.10": "PBL ISP maintained dynamic range",
+			"127.0.0.11": "PBL Spamhaus maintained dynamic range",
+		},
+		errors: spamhausErrors,
+	},
+	"multi.surbl.org": {
+		bits: map[byte]string{8: "phishing", 16: "malware", 64: "abused", 128: "cracked site"},
+		errors: map[string]error{
+			"127.0.0.1": providers.NewError("SURBL", providers.ErrAuth, "the query was refused, the resolver is blocked"),
+		},
+	},
+	"multi.uribl.com": {
+		bits: map[byte]string{2: "black", 4: "grey", 8: "red"},
+		errors: map[string]error{
+			"127.0.0.1": providers.NewError("URIBL", providers.ErrAuth, "the query was refused, the resolver is blocked"),
+		},
+	},
+	"bl.spamcop.net": {
+		codes: map[string]string{"127.0.0.2": "spam source"},
+	},
+	"b.barracudacentral.org": {
+		codes: map[string]string{"127.0.0.2": "poor reputation"},
+	},
+}
+
+// Decode the address a list returned for a query into an entry with the reason of the listing.
+// An error is returned when the code means the query was refused.
+func decode(zone string, address net.IP) (Entry, error) {
+	code := address.String()
+	entry := Entry{Code: code, Reason: "listed (" + code + ")"}
+	if address.To4() == nil || address.To4()[0] != 127 {
+		return Entry{}, fmt.Errorf("%s returned %s which is not a list return code", zone, code)
+	}
+
+	d, ok := decoders[strings.ToLower(zone)]
+	if !ok {
+		return entry, nil
+	}
+	if err, ok := d.errors[code]; ok {
+		return Entry{}, err
+	}
+	if reason, ok := d.codes[code]; ok {
+		entry.Reason = reason
+		return entry, nil
+	}
+	if reason, ok := d.info[code]; ok {
+		entry.Reason = reason
+		entry.Informational = true
+		return entry, nil
+	}
+
+	if d.bits != nil {
+		last := address.To4()[3]
+		var reasons []string
+		for _, bit := range slices.Sorted(maps.Keys(d.bits)) {
+			if last&bit != 0 {
+				reasons = append(reasons, d.bits[bit])
+			}
+		}
+		if len(reasons) > 0 {
+			entry.Reason = strings.Join(reasons, ", ")
+		}
+	}
+
+	return entry, nil
+}
+
+// Name to look up on a list: the domain for domain lists and the reversed octets for IP lists,
+// e.g. 192.0.2.1 is looked up as 1.2.0.192.zen.spamhaus.org
+func queryName(zone string, listType string, entry string) string {
+	if listType == ListIP {
+		octets := strings.Split(entry, ".")
+		slices.Reverse(octets)
+		entry = strings.Join(octets, ".")
+	}
+	return entry + "." + zone + "."
+}
@@ -0,0 +1,129 @@
+package dnsbl
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lum8rjack/redcompass/health"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Store saves the result of each list for a domain to the Blocklist_Listings collection
+type Store struct{}
+
+// Save the result of each list for a domain. Listed_Since is kept while the domain stays listed.
+// The results of lists that are no longer configured are deleted so they do not count against
+// the health policy.
+func (Store) SaveResults(app core.App, data []byte) (string, error) {
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		return "", err
+	}
+
+	domain, err := app.FindFirstRecordByData("Domains", "Name", results.Domain)
+	if err != nil {
+		return "", err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("Blocklist_Listings")
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	lists := make([]any, 0, len(results.Listings))
+	for _, listing := range results.Listings {
+		lists = append(lists, listing.List)
+
+		record, err := app.FindFirstRecordByFilter("Blocklist_Listings",
+			"Domain = {:domain} && List = {:list}",
+			dbx.Params{"domain": domain.Id, "list": listing.List},
+		)
+		if err != nil {
+			record = core.NewRecord(collection)
+			record.Set("Domain", domain.Id)
+			record.Set("List", listing.List)
+		}
+
+		// A list that could not be queried keeps its previous result
+		record.Set("Type", listing.Type)
+		record.Set("Error", listing.Error)
+		if listing.Error == "" {
+			if listing.Listed && !record.GetBool("Listed") {
+				record.Set("Listed_Since", now)
+			}
+			if !listing.Listed {
+				record.Set("Listed_Since", "")
+			}
+			record.Set("Listed", listing.Listed)
+			record.Set("Entries", listing.Entries)
+			record.Set("Last_Checked", now)
+		}
+
+		if err := app.Save(record); err != nil {
+			return "", err
+		}
+	}
+
+	removed, err := app.FindAllRecords("Blocklist_Listings",
+		dbx.HashExp{"Domain": domain.Id},
+		dbx.NotIn("List", lists...),
+	)
+	if err != nil {
+		return "", err
+	}
+	for _, record := range removed {
+		if err := app.Delete(record); err != nil {
+			return "", err
+		}
+	}
+	return results.Domain, nil
+}
+
+// Time each domain was last checked on any list
+func (Store) LastScans(app core.App) (map[string]time.Time, error) {
+	var rows []struct {
+		Domain  string `db:"Domain"`
+		Checked string `db:"checked"`
+	}
+	err := app.DB().Select("Domains.Name AS Domain", "MAX(Blocklist_Listings.Last_Checked) AS checked").
+		From("Blocklist_Listings").
+		InnerJoin("Domains", dbx.NewExp("Domains.id = Blocklist_Listings.Domain")).
+		GroupBy("Domains.Name").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		checked, err := types.ParseDateTime(row.Checked)
+		if err != nil {
+			return nil, err
+		}
+		times[row.Domain] = checked.Time()
+	}
+	return times, nil
+}
+
+// Reasons of the listings of a domain on each list it is listed on, keyed by the zone
+func (Store) HealthResults(app core.App, domain *core.Record) (health.Results, error) {
+	records, err := app.FindAllRecords("Blocklist_Listings",
+		dbx.HashExp{"Domain": domain.Id, "Listed": true},
+	)
+	if err != nil {
+		return health.Results{}, err
+	}
+
+	listed := map[string][]string{}
+	for _, record := range records {
+		listing := Listing{List: record.GetString("List"), Type: record.GetString("Type")}
+		if err := record.UnmarshalJSONField("Entries", &listing.Entries); err != nil {
+			return health.Results{}, err
+		}
+		listed[listing.List] = listing.Reasons()
+	}
+	return health.Results{Blocklists: listed}, nil
+}
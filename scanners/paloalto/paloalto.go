@@ -0,0 +1,170 @@
+// Package paloalto looks up the PAN-DB URL filtering category of a domain with the XML API of
+// a Palo Alto Networks firewall or Panorama.
+package paloalto
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lum8rjack/redcompass/categorization"
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/scanners/types"
+)
+
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     "Palo Alto",
+		Kind:     providers.KindChecker,
+		Settings: settingsSchema,
+		NewChecker: func(settings string) (types.Checker, error) {
+			return NewClient(settings)
+		},
+	})
+}
+
+type Client struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+type pa_settings struct {
+	BaseURL string `json:"baseUrl"`
+	APIKey  string `json:"apiKey"`
+}
+
+// Response of the XML API, the result of "test url" is text and errors have a message
+type pa_response struct {
+	Status string `xml:"status,attr"`
+	Code   string `xml:"code,attr"`
+	Result struct {
+		Text string   `xml:",chardata"`
+		Msg  []string `xml:"msg"`
+	} `xml:"result"`
+	Msg []string `xml:"msg>line"`
+}
+
+func NewClient(settings string) (*Client, error) {
+	if settings == "" {
+		return nil, errors.New("settings are empty")
+	}
+
+	var paSettings pa_settings
+	if err := json.Unmarshal([]byte(settings), &paSettings); err != nil {
+		return nil, err
+	}
+	if paSettings.BaseURL == "" {
+		return nil, errors.New("firewall URL is empty")
+	}
+	if paSettings.APIKey == "" {
+		return nil, errors.New("API key is empty")
+	}
+
+	return &Client{
+		client:  providers.NewHTTPClient("Palo Alto", providers.ClientOptions{}),
+		baseURL: strings.TrimSuffix(paSettings.BaseURL, "/"),
+		apiKey:  paSettings.APIKey,
+	}, nil
+}
+
+// GetName returns the name of the vendor
+func (c *Client) GetName() string {
+	return "Palo Alto"
+}
+
+// GetCategory returns the PAN-DB category of the domain using the "test url" operational command
+func (c *Client) GetCategory(ctx context.Context, domain string) (string, error) {
+	query := url.Values{}
+	query.Set("type", "op")
+	query.Set("cmd", "<test><url>"+xmlEscape(domain)+"</url></test>")
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/?"+query.Encode(), nil)
+	req.Header.Add("X-PAN-KEY", c.apiKey)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", providers.FromRequestError("Palo Alto", err)
+	}
+	defer res.Body.Close()
+
+	var response pa_response
+	decodeErr := xml.NewDecoder(res.Body).Decode(&response)
+
+	if res.StatusCode != 200 {
+		return "", providers.FromResponse("Palo Alto", res, response.message())
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("failed to parse the Palo Alto response: %w", decodeErr)
+	}
+	if response.Status != "success" {
+		if response.Code == "403" {
+			return "", providers.NewError("Palo Alto", providers.ErrAuth, response.message())
+		}
+		return "", fmt.Errorf("Palo Alto: %s", response.message())
+	}
+
+	return parseCategory(domain, response.Result.Text)
+}
+
+// Parse the result of "test url", one line per database:
+//
+//	example.com business-and-economy low-risk (Base db) expires in 2000 seconds
+//	example.com business-and-economy low-risk (Cloud db)
+//
+// The cloud database is the most recent so it is used when the firewall returns both. Newer
+// versions add risk categories after the URL category.
+func parseCategory(domain string, result string) (string, error) {
+	var categories []string
+	for _, line := range strings.Split(result, "\n") {
+		line = strings.TrimSpace(line)
+		open := strings.Index(line, "(")
+		if open < 0 {
+			continue
+		}
+
+		fields := strings.Fields(line[:open])
+		if len(fields) < 2 || fields[1] == "not-resolved" {
+			continue
+		}
+		categories = fields[1:]
+		if strings.HasPrefix(line[open:], "(Cloud db)") {
+			break
+		}
+	}
+
+	// Only this domain failed, so the check goes on with the other domains
+	if len(categories) == 0 {
+		return "", providers.NewError("Palo Alto", providers.ErrNotFound, "the category of "+domain+" could not be resolved")
+	}
+	if categorization.IsUncategorized(categories[0]) {
+		return types.Uncategorized, nil
+	}
+	return strings.Join(categories, ", "), nil
+}
+
+// Error message of the response
+func (r pa_response) message() string {
+	msg := append(r.Result.Msg, r.Msg...)
+	if len(msg) == 0 {
+		return strings.TrimSpace(r.Result.Text)
+	}
+	return strings.Join(msg, " ")
+}
+
+func xmlEscape(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
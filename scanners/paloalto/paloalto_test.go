@@ -0,0 +1,50 @@
+package paloalto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/providers/providertest"
+	"github.com/lum8rjack/redcompass/scanners/types"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunCheckerTests(t, providertest.CheckerSuite{
+		Handler: providertest.PaloAltoHandler,
+		New: func(t *testing.T, baseURL string) types.Checker {
+			c, err := NewClient(`{"apiKey":"` + providertest.PaloAltoAPIKey + `","baseUrl":"` + baseURL + `"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return c
+		},
+	})
+}
+
+func TestParseCategory(t *testing.T) {
+	tests := []struct {
+		result string
+		want   string
+	}{
+		{"example.com business-and-economy (Base db) expires in 2000 seconds\nexample.com computer-and-internet-info (Cloud db)", "computer-and-internet-info"},
+		{"example.com business-and-economy low-risk (Base db) expires in 2000 seconds", "business-and-economy, low-risk"},
+		{"example.com not-resolved (Base db) expires in 5 seconds\nexample.com unknown (Cloud db)", types.Uncategorized},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCategory("example.com", tt.result)
+		if err != nil {
+			t.Fatalf("parseCategory(%q): %v", tt.result, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseCategory(%q) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+
+	// Not resolving one domain must not stop the check of the other domains
+	_, err := parseCategory("example.com", "example.com not-resolved (Base db)")
+	if !errors.Is(err, providers.ErrNotFound) || providers.IsRetryable(err) {
+		t.Errorf("expected a not found error when the category is not resolved, got %v", err)
+	}
+}
@@ -3,15 +3,32 @@ package scanners
 import (
 	"errors"
 
+	"github.com/lum8rjack/redcompass/providers"
 	"github.com/lum8rjack/redcompass/scanners/types"
-	"github.com/lum8rjack/redcompass/scanners/virustotal"
+
+	// Scanners and checkers register themselves with the providers registry
+	_ "github.com/lum8rjack/redcompass/scanners/dnsbl"
+	_ "github.com/lum8rjack/redcompass/scanners/paloalto"
+	_ "github.com/lum8rjack/redcompass/scanners/talos"
+	_ "github.com/lum8rjack/redcompass/scanners/virustotal"
 )
 
+// Create the client for a scanner from the providers registry
 func NewScanner(provider string, settings string) (types.Scanner, error) {
+	p, ok := providers.Lookup(provider)
+	if !ok || p.Kind != providers.KindScanner {
+		return nil, errors.New("invalid scanner")
+	}
+
+	return p.NewScanner(settings)
+}
 
-	if provider == "VirusTotal" {
-		return virustotal.NewClient(settings)
+// Create the client for a categorisation checker from the providers registry
+func NewChecker(provider string, settings string) (types.Checker, error) {
+	p, ok := providers.Lookup(provider)
+	if !ok || p.Kind != providers.KindChecker {
+		return nil, errors.New("invalid checker")
 	}
 
-	return nil, errors.New("invalid scanner")
+	return p.NewChecker(settings)
 }
@@ -0,0 +1,117 @@
+// Package talos looks up the web category Cisco Talos gives a domain using the reputation
+// center at talosintelligence.com.
+package talos
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/categorization"
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/scanners/types"
+	"golang.org/x/time/rate"
+)
+
+// Default URL of the reputation center, can be changed in the settings to use a proxy or a test server
+const defaultBaseURL = "https://talosintelligence.com"
+
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     "Cisco Talos",
+		Kind:     providers.KindChecker,
+		Settings: settingsSchema,
+		NewChecker: func(settings string) (types.Checker, error) {
+			return NewClient(settings)
+		},
+	})
+}
+
+type Client struct {
+	client  *http.Client
+	baseURL string
+	limiter *rate.Limiter
+}
+
+type talos_settings struct {
+	BaseURL string `json:"baseUrl"`
+}
+
+// Details of a domain returned by the reputation center
+type talos_details struct {
+	Category *struct {
+		Description string `json:"description"`
+	} `json:"category"`
+}
+
+func NewClient(settings string) (*Client, error) {
+	var talosSettings talos_settings
+	if settings != "" {
+		if err := json.Unmarshal([]byte(settings), &talosSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	baseURL := defaultBaseURL
+	if talosSettings.BaseURL != "" {
+		baseURL = strings.TrimSuffix(talosSettings.BaseURL, "/")
+	}
+
+	// The reputation center is a public website, keep the lookups slow to avoid being blocked
+	return &Client{
+		client:  providers.NewHTTPClient("Cisco Talos", providers.ClientOptions{}),
+		baseURL: baseURL,
+		limiter: rate.NewLimiter(rate.Every(10*time.Second), 1),
+	}, nil
+}
+
+// GetName returns the name of the vendor
+func (c *Client) GetName() string {
+	return categorization.Normalize("Cisco Talos")
+}
+
+// GetCategory returns the web category Talos gives the domain
+func (c *Client) GetCategory(ctx context.Context, domain string) (string, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("query", "/api/v2/details/domain/")
+	query.Set("query_entry", domain)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/sb_api/query_lookup?"+query.Encode(), nil)
+	req.Header.Add("accept", "application/json")
+	// The lookup API only answers requests coming from the reputation center page
+	req.Header.Add("referer", c.baseURL+"/reputation_center/lookup?search="+url.QueryEscape(domain))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", providers.FromRequestError("Cisco Talos", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return "", providers.FromResponse("Cisco Talos", res, "")
+	}
+
+	var details talos_details
+	if err := json.NewDecoder(res.Body).Decode(&details); err != nil {
+		return "", fmt.Errorf("failed to parse the Talos response: %w", err)
+	}
+
+	if details.Category == nil || categorization.IsUncategorized(details.Category.Description) {
+		return types.Uncategorized, nil
+	}
+	return details.Category.Description, nil
+}
@@ -0,0 +1,23 @@
+package talos
+
+import (
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers/providertest"
+	"github.com/lum8rjack/redcompass/scanners/types"
+	"golang.org/x/time/rate"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunCheckerTests(t, providertest.CheckerSuite{
+		Handler: providertest.TalosHandler,
+		New: func(t *testing.T, baseURL string) types.Checker {
+			c, err := NewClient(`{"baseUrl":"` + baseURL + `"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.limiter = rate.NewLimiter(rate.Inf, 1)
+			return c
+		},
+	})
+}
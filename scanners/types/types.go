@@ -1,8 +1,23 @@
 package types
 
+import "context"
+
 type Scanner interface {
 	GetName() string
-	IsKeyValid() bool
-	GetResults(domain string) ([]byte, error)
-	GetDailyQuotaRemaining() (int, error)
+	ValidateKey(ctx context.Context) error
+	GetResults(ctx context.Context, domain string) ([]byte, error)
+	GetDailyQuotaRemaining(ctx context.Context) (int, error)
+}
+
+// Checker looks up the category a web filtering vendor gives a domain
+type Checker interface {
+	// Name of the vendor, saved as the Vendor of the Categorizations records
+	GetName() string
+
+	// GetCategory returns the category of the domain, or "uncategorized" when the vendor has
+	// not categorised it yet
+	GetCategory(ctx context.Context, domain string) (string, error)
 }
+
+// Category returned by a checker for a domain the vendor has not categorised
+const Uncategorized = "uncategorized"
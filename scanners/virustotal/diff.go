@@ -0,0 +1,57 @@
+package virustotal
+
+import "slices"
+
+// Verdict of an engine that was not part of a scan
+const VerdictNotScanned = "not scanned"
+
+// VT_Engine_Change is a change of an engine verdict between two scans of a domain.
+type VT_Engine_Change struct {
+	Engine string `json:"engine"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// String formats the change as e.g. "Fortinet: clean → phishing".
+func (c VT_Engine_Change) String() string {
+	return c.Engine + ": " + c.Before + " → " + c.After
+}
+
+// Verdict returns the result of an engine, e.g. "clean" or "phishing", and falls back to the
+// category when the engine did not report a result.
+func Verdict(r VT_Analysis_Engine_Result) string {
+	if r.Result != "" {
+		return r.Result
+	}
+	return r.Category
+}
+
+// DiffResults compares the engine verdicts of two scans and returns the engines whose verdict
+// changed, sorted by engine name.
+func DiffResults(previous, current map[string]VT_Analysis_Engine_Result) []VT_Engine_Change {
+	engines := make([]string, 0, len(current))
+	for engine := range current {
+		engines = append(engines, engine)
+	}
+	for engine := range previous {
+		if _, ok := current[engine]; !ok {
+			engines = append(engines, engine)
+		}
+	}
+	slices.Sort(engines)
+
+	changes := []VT_Engine_Change{}
+	for _, engine := range engines {
+		before, after := VerdictNotScanned, VerdictNotScanned
+		if r, ok := previous[engine]; ok {
+			before = Verdict(r)
+		}
+		if r, ok := current[engine]; ok {
+			after = Verdict(r)
+		}
+		if before != after {
+			changes = append(changes, VT_Engine_Change{Engine: engine, Before: before, After: after})
+		}
+	}
+	return changes
+}
@@ -10,15 +10,14 @@ type VT_Response struct {
 			Self string `json:"self"`
 		} `json:"links"`
 		Attributes struct {
-			WhoisDate          int    `json:"whois_date"`
-			Tld                string `json:"tld"`
-			LastDNSRecordsDate int    `json:"last_dns_records_date"`
-			LastAnalysisDate   int    `json:"last_analysis_date"`
-			Categories         struct {
-			} `json:"categories"`
-			LastUpdateDate int    `json:"last_update_date"`
-			Registrar      string `json:"registrar"`
-			TotalVotes     struct {
+			WhoisDate          int               `json:"whois_date"`
+			Tld                string            `json:"tld"`
+			LastDNSRecordsDate int               `json:"last_dns_records_date"`
+			LastAnalysisDate   int               `json:"last_analysis_date"`
+			Categories         map[string]string `json:"categories"`
+			LastUpdateDate     int               `json:"last_update_date"`
+			Registrar          string            `json:"registrar"`
+			TotalVotes         struct {
 				Harmless  int `json:"harmless"`
 				Malicious int `json:"malicious"`
 			} `json:"total_votes"`
@@ -135,8 +134,6 @@ type VT_Response struct {
 						Type       string   `json:"type"`
 						Values     []string `json:"values"`
 						Parameters struct {
-						} `json:"parameters,omitempty"`
-						Parameters0 struct {
 							Type []string `json:"type"`
 						} `json:"parameters,omitempty"`
 					} `json:"vcard_array"`
@@ -152,8 +149,6 @@ type VT_Response struct {
 							Type       string   `json:"type"`
 							Values     []string `json:"values"`
 							Parameters struct {
-							} `json:"parameters,omitempty"`
-							Parameters0 struct {
 								Type []string `json:"type"`
 							} `json:"parameters,omitempty"`
 						} `json:"vcard_array"`
@@ -789,18 +784,7 @@ type VT_Response struct {
 					Result     string `json:"result"`
 				} `json:"ZeroFox"`
 			} `json:"last_analysis_results"`
-			LastDNSRecords []struct {
-				Type     string `json:"type"`
-				TTL      int    `json:"ttl"`
-				Priority int    `json:"priority,omitempty"`
-				Value    string `json:"value"`
-				Rname    string `json:"rname,omitempty"`
-				Serial   int64  `json:"serial,omitempty"`
-				Refresh  int    `json:"refresh,omitempty"`
-				Retry    int    `json:"retry,omitempty"`
-				Expire   int    `json:"expire,omitempty"`
-				Minimum  int    `json:"minimum,omitempty"`
-			} `json:"last_dns_records"`
+			LastDNSRecords  []VT_DNS_Record `json:"last_dns_records"`
 			PopularityRanks struct {
 			} `json:"popularity_ranks"`
 			Reputation   int `json:"reputation"`
@@ -819,6 +803,34 @@ type VT_Database struct {
 	Harmless            int                                  `json:"harmless"`
 	Timeout             int                                  `json:"timeout"`
 	LastAnalysisResults map[string]VT_Analysis_Engine_Result `json:"last_analysis_results"`
+
+	// Category of the domain reported by each vendor, e.g. "Forcepoint ThreatSeeker": "information technology"
+	Categories     map[string]string `json:"categories"`
+	Certificate    VT_Certificate    `json:"certificate"`
+	Jarm           string            `json:"jarm"`
+	LastDNSRecords []VT_DNS_Record   `json:"last_dns_records"`
+}
+
+// VT_Certificate holds the issuer and validity of the last HTTPS certificate of a domain.
+type VT_Certificate struct {
+	Issuer    string `json:"issuer"`
+	Subject   string `json:"subject"`
+	NotBefore string `json:"not_before"`
+	NotAfter  string `json:"not_after"`
+}
+
+// VT_DNS_Record is a DNS record VirusTotal saw for a domain.
+type VT_DNS_Record struct {
+	Type     string `json:"type"`
+	TTL      int    `json:"ttl"`
+	Priority int    `json:"priority,omitempty"`
+	Value    string `json:"value"`
+	Rname    string `json:"rname,omitempty"`
+	Serial   int64  `json:"serial,omitempty"`
+	Refresh  int    `json:"refresh,omitempty"`
+	Retry    int    `json:"retry,omitempty"`
+	Expire   int    `json:"expire,omitempty"`
+	Minimum  int    `json:"minimum,omitempty"`
 }
 
 type VT_Analysis_Engine_Result struct {
@@ -828,16 +840,29 @@ type VT_Analysis_Engine_Result struct {
 	Result     string `json:"result"`
 }
 
-// VT_QuotaPathCounts maps API path patterns (e.g. "/api/v3/(domains)") to request counts.
-type VT_QuotaPathCounts map[string]int
-
-// VT_QuotaResponse is the JSON envelope for VirusTotal usage / daily quota payloads.
+// VT_QuotaResponse is the JSON envelope of the overall quotas of a user.
 type VT_QuotaResponse struct {
-	Data VT_QuotaData `json:"data"`
+	Data struct {
+		APIRequestsDaily VT_Quota `json:"api_requests_daily"`
+	} `json:"data"`
+}
+
+// VT_Quota holds the usage of the user and of the group the user belongs to, if any.
+type VT_Quota struct {
+	User  VT_QuotaUsage `json:"user"`
+	Group VT_QuotaUsage `json:"group"`
+}
+
+// VT_QuotaUsage holds the number of requests used and allowed.
+type VT_QuotaUsage struct {
+	Used    int `json:"used"`
+	Allowed int `json:"allowed"`
 }
 
-// VT_QuotaData holds cumulative totals and per-day usage keyed by calendar date (YYYY-MM-DD).
-type VT_QuotaData struct {
-	Total VT_QuotaPathCounts            `json:"total"`
-	Daily map[string]VT_QuotaPathCounts `json:"daily"`
+// VT_ErrorResponse is the JSON envelope VirusTotal returns with unsuccessful responses.
+type VT_ErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
 }
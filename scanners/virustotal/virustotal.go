@@ -2,26 +2,61 @@ package virustotal
 
 import (
 	"context"
+	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/scanners/types"
 	"golang.org/x/time/rate"
 )
 
+// Default URL of the API, can be changed in the settings to use a proxy or a test server
+const defaultBaseURL = "https://www.virustotal.com/api/v3"
+
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     "VirusTotal",
+		Kind:     providers.KindScanner,
+		Settings: settingsSchema,
+		NewScanner: func(settings string) (types.Scanner, error) {
+			return NewClient(settings)
+		},
+	})
+}
+
+// Plans of the VirusTotal API. The public API is limited to 4 requests a minute, premium
+// keys only have the daily quota of the account.
+const (
+	TierPublic  = "public"
+	TierPremium = "premium"
+)
+
 type Client struct {
 	client           *http.Client
+	baseURL          string
 	apiKey           string
 	username         string
+	dailyBudget      int
 	perMinuteLimiter *rate.Limiter
 }
 
 type vt_settings struct {
-	APIKey   string `json:"apiKey"`
-	Username string `json:"username"`
+	APIKey      string `json:"apiKey"`
+	Username    string `json:"username"`
+	BaseURL     string `json:"baseUrl"`
+	Tier        string `json:"tier"`
+	DailyBudget int    `json:"dailyBudget"`
 }
 
 func NewClient(settings string) (*Client, error) {
@@ -42,16 +77,33 @@ func NewClient(settings string) (*Client, error) {
 		return nil, errors.New("username is empty")
 	}
 
-	webclient := &http.Client{
-		Timeout: 10 * time.Second,
+	if vtSettings.DailyBudget < 0 {
+		return nil, errors.New("daily budget can not be negative")
+	}
+
+	baseURL := defaultBaseURL
+	if vtSettings.BaseURL != "" {
+		baseURL = strings.TrimSuffix(vtSettings.BaseURL, "/")
+	}
+
+	// Public API limit: 4/min, the daily limit comes from the quota of the account
+	var perMinuteLimiter *rate.Limiter
+	switch vtSettings.Tier {
+	case "", TierPublic:
+		perMinuteLimiter = rate.NewLimiter(rate.Every(time.Minute/4), 1)
+	case TierPremium:
+		perMinuteLimiter = rate.NewLimiter(rate.Inf, 1)
+	default:
+		return nil, errors.New("invalid tier")
 	}
 
-	// VirusTotal limit: 4/min and 500/day
 	return &Client{
-		client:           webclient,
+		client:           providers.NewHTTPClient("VirusTotal", providers.ClientOptions{}),
+		baseURL:          baseURL,
 		apiKey:           vtSettings.APIKey,
 		username:         vtSettings.Username,
-		perMinuteLimiter: rate.NewLimiter(rate.Every(time.Minute/4), 1),
+		dailyBudget:      vtSettings.DailyBudget,
+		perMinuteLimiter: perMinuteLimiter,
 	}, nil
 }
 
@@ -60,39 +112,41 @@ func (c *Client) GetName() string {
 	return "VirusTotal"
 }
 
-// IsKeyValid checks if the API key is valid
-func (c *Client) IsKeyValid() bool {
-	req, _ := http.NewRequest("GET", "https://www.virustotal.com/api/v3/metadata", nil)
+// ValidateKey checks if the API key is valid
+func (c *Client) ValidateKey(ctx context.Context) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/metadata", nil)
 	req.Header.Add("accept", "application/json")
 	req.Header.Add("x-apikey", c.apiKey)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
-		return false
+		return providers.FromRequestError("VirusTotal", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
-		return false
+		return apiError(res)
 	}
 
-	return true
+	return nil
 }
 
-// GetDailyQuotaRemaining returns the daily quota remaining
-func (c *Client) GetDailyQuotaRemaining() (int, error) {
-	url := fmt.Sprintf("https://www.virustotal.com/api/v3/users/%s/api_usage", c.username)
-	req, _ := http.NewRequest("GET", url, nil)
+// GetDailyQuotaRemaining returns the requests remaining today. All API requests of the account
+// count against the quota, which is the daily allowance of the plan or the daily budget from the
+// settings when it is lower.
+func (c *Client) GetDailyQuotaRemaining(ctx context.Context) (int, error) {
+	url := fmt.Sprintf("%s/users/%s/overall_quotas", c.baseURL, c.username)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Add("accept", "application/json")
 	req.Header.Add("x-apikey", c.apiKey)
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
-		return 0, err
+		return 0, providers.FromRequestError("VirusTotal", err)
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
-		return 0, errors.New("failed to get daily quota remaining")
+		return 0, apiError(res)
 	}
 
 	body, err := io.ReadAll(res.Body)
@@ -105,43 +159,46 @@ func (c *Client) GetDailyQuotaRemaining() (int, error) {
 		return 0, err
 	}
 
-	// Get the current date in YYYY-MM-DD format based on UTC time
-	now := time.Now().UTC()
-	date := now.Format("2006-01-02")
+	daily := response.Data.APIRequestsDaily
+	if daily.User.Allowed <= 0 {
+		return 0, errors.New("failed to get daily quota")
+	}
+
+	remaining := daily.User.Allowed - daily.User.Used
 
-	// Get the daily quota usage for the current date
-	dailyUsage := response.Data.Daily[date]
-	if dailyUsage == nil {
-		return 0, errors.New("failed to get daily quota usage")
+	// Premium keys can share the quota of their group
+	if daily.Group.Allowed > 0 {
+		remaining = min(remaining, daily.Group.Allowed-daily.Group.Used)
 	}
 
-	remaining := 500 - dailyUsage["/api/v3/(domains)"]
+	if c.dailyBudget > 0 {
+		remaining = min(remaining, c.dailyBudget-daily.User.Used)
+	}
 
-	return remaining, nil
+	return max(remaining, 0), nil
 }
 
 // GetResults returns the domain reputation results for a domain
-func (c *Client) GetResults(domain string) ([]byte, error) {
+func (c *Client) GetResults(ctx context.Context, domain string) ([]byte, error) {
 	// Wait for rate limiter
-	if err := c.waitForRateLimit(); err != nil {
+	if err := c.waitForRateLimit(ctx); err != nil {
 		return nil, err
 	}
 
 	// Setup request
-	url := fmt.Sprintf("https://www.virustotal.com/api/v3/domains/%s", domain)
-	req, _ := http.NewRequest("GET", url, nil)
+	url := fmt.Sprintf("%s/domains/%s", c.baseURL, domain)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Add("accept", "application/json")
 	req.Header.Add("x-apikey", c.apiKey)
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, providers.FromRequestError("VirusTotal", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
-		e := fmt.Sprintf("request returned a status code of %d", res.StatusCode)
-		return nil, errors.New(e)
+		return nil, apiError(res)
 	}
 
 	body, err := io.ReadAll(res.Body)
@@ -170,11 +227,61 @@ func (c *Client) GetResults(domain string) ([]byte, error) {
 		Harmless:            response.Data.Attributes.LastAnalysisStats.Harmless,
 		Timeout:             response.Data.Attributes.LastAnalysisStats.Timeout,
 		LastAnalysisResults: lastAnalysis,
+		Categories:          response.Data.Attributes.Categories,
+		Jarm:                response.Data.Attributes.Jarm,
+		LastDNSRecords:      response.Data.Attributes.LastDNSRecords,
+	}
+	if vtresults.Categories == nil {
+		vtresults.Categories = map[string]string{}
+	}
+	if vtresults.LastDNSRecords == nil {
+		vtresults.LastDNSRecords = []VT_DNS_Record{}
+	}
+
+	certificate := response.Data.Attributes.LastHTTPSCertificate
+	vtresults.Certificate = VT_Certificate{
+		Issuer:    distinguishedName(certificate.Issuer.Cn, certificate.Issuer.O, certificate.Issuer.C),
+		Subject:   distinguishedName(certificate.Subject.Cn, certificate.Subject.O, certificate.Subject.C),
+		NotBefore: certificate.Validity.NotBefore,
+		NotAfter:  certificate.Validity.NotAfter,
 	}
 
 	return json.Marshal(vtresults)
 }
 
+// Format the name of a certificate issuer or subject, e.g. "CN=R11, O=Let's Encrypt, C=US"
+func distinguishedName(cn, o, c string) string {
+	var parts []string
+	for _, part := range [][2]string{{"CN", cn}, {"O", o}, {"C", c}} {
+		if part[1] != "" {
+			parts = append(parts, part[0]+"="+part[1])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Classify a failed response using the error code in the body. VirusTotal returns a
+// QuotaExceededError for both the per minute and the daily limits.
+func apiError(res *http.Response) error {
+	var response VT_ErrorResponse
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	message := fmt.Sprintf("request returned a status code of %d", res.StatusCode)
+	if response.Error.Message != "" {
+		message = response.Error.Code + ": " + response.Error.Message
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter := providers.ParseRetryAfter(res.Header.Get("Retry-After"))
+		if retryAfter == 0 {
+			retryAfter = time.Minute
+		}
+		return providers.RateLimited("VirusTotal", retryAfter, message)
+	}
+
+	return providers.FromResponse("VirusTotal", res, message)
+}
+
 // convertLastAnalysisResultsToMap turns VT_Response's fixed-field last_analysis_results
 // into a map keyed by engine id, using JSON so we do not list every engine by hand.
 func convertLastAnalysisResultsToMap(v any) (map[string]VT_Analysis_Engine_Result, error) {
@@ -193,8 +300,13 @@ func convertLastAnalysisResultsToMap(v any) (map[string]VT_Analysis_Engine_Resul
 }
 
 // Wait for the rate limiters
-func (c *Client) waitForRateLimit() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	// Stop right away if the job was cancelled instead of reporting a rate limit error
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
 	if err := c.perMinuteLimiter.Wait(ctx); err != nil {
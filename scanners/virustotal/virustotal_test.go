@@ -0,0 +1,135 @@
+package virustotal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers/providertest"
+	"github.com/lum8rjack/redcompass/scanners/types"
+	"golang.org/x/time/rate"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunScannerTests(t, providertest.ScannerSuite{
+		Handler: providertest.VirusTotalHandler,
+		New: func(t *testing.T, baseURL string) types.Scanner {
+			c, err := NewClient(`{"apiKey":"` + strings.Repeat("a", 64) + `","username":"test","baseUrl":"` + baseURL + `"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.perMinuteLimiter = rate.NewLimiter(rate.Inf, 1)
+			return c
+		},
+	})
+}
+
+func TestDiffResults(t *testing.T) {
+	previous := map[string]VT_Analysis_Engine_Result{
+		"Fortinet": {Category: "harmless", Result: "clean"},
+		"Sophos":   {Category: "harmless", Result: "clean"},
+		"Webroot":  {Category: "undetected", Result: "unrated"},
+	}
+	current := map[string]VT_Analysis_Engine_Result{
+		"Fortinet":  {Category: "malicious", Result: "phishing"},
+		"Sophos":    {Category: "harmless", Result: "clean"},
+		"Kaspersky": {Category: "timeout"},
+	}
+
+	var got []string
+	for _, c := range DiffResults(previous, current) {
+		got = append(got, c.String())
+	}
+
+	want := []string{
+		"Fortinet: clean → phishing",
+		"Kaspersky: not scanned → timeout",
+		"Webroot: unrated → not scanned",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffResults() = %q, want %q", got, want)
+	}
+
+	if changes := DiffResults(current, current); len(changes) != 0 {
+		t.Errorf("DiffResults() of the same scan = %v, want no changes", changes)
+	}
+}
+
+func TestDailyBudget(t *testing.T) {
+	scans := providertest.NewScans()
+	scans.SetUsed(100)
+	srv := httptest.NewServer(providertest.VirusTotalHandler(scans))
+	defer srv.Close()
+
+	tests := []struct {
+		budget int
+		want   int
+	}{
+		{budget: 0, want: 400},
+		{budget: 150, want: 50},
+		{budget: 50, want: 0},
+	}
+
+	for _, tt := range tests {
+		c, err := NewClient(`{"apiKey":"` + strings.Repeat("a", 64) + `","username":"test","tier":"premium","dailyBudget":` + strconv.Itoa(tt.budget) + `,"baseUrl":"` + srv.URL + `"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.GetDailyQuotaRemaining(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("budget %d: GetDailyQuotaRemaining() = %d, want %d", tt.budget, got, tt.want)
+		}
+	}
+
+	if _, err := NewClient(`{"apiKey":"` + strings.Repeat("a", 64) + `","username":"test","tier":"enterprise"}`); err == nil {
+		t.Error("expected an error for an invalid tier")
+	}
+}
+
+func TestResultDetails(t *testing.T) {
+	scans := providertest.NewScans()
+	scans.SetResult("example.com", providertest.ScanResult{
+		Harmless:   60,
+		Categories: map[string]string{"Forcepoint ThreatSeeker": "information technology", "Sophos": "information technology"},
+	})
+	srv := httptest.NewServer(providertest.VirusTotalHandler(scans))
+	defer srv.Close()
+
+	c, err := NewClient(`{"apiKey":"` + strings.Repeat("a", 64) + `","username":"test","tier":"premium","baseUrl":"` + srv.URL + `"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := c.GetResults(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results VT_Database
+	if err := json.Unmarshal(body, &results); err != nil {
+		t.Fatal(err)
+	}
+	if results.Categories["Forcepoint ThreatSeeker"] != "information technology" || len(results.Categories) != 2 {
+		t.Errorf("unexpected categories %v", results.Categories)
+	}
+	if results.Jarm != providertest.VirusTotalJarm {
+		t.Errorf("Jarm = %q", results.Jarm)
+	}
+	want := VT_Certificate{
+		Issuer:    "CN=" + providertest.VirusTotalCertIssuer + ", O=Let's Encrypt, C=US",
+		Subject:   "CN=example.com",
+		NotBefore: providertest.VirusTotalCertNotBefore,
+		NotAfter:  providertest.VirusTotalCertNotAfter,
+	}
+	if results.Certificate != want {
+		t.Errorf("Certificate = %+v, want %+v", results.Certificate, want)
+	}
+	if len(results.LastDNSRecords) != 1 || results.LastDNSRecords[0].Value != providertest.VirusTotalDNSRecordValue {
+		t.Errorf("unexpected DNS records %+v", results.LastDNSRecords)
+	}
+}
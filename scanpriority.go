@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Assigned domains scanned within this time lose their priority, so they can not use up the
+// whole budget every run
+const assignedScanInterval = 24 * time.Hour
+
+// Order the domains to scan when the budget does not cover all of them. Assigned domains that
+// were not scanned in the last day come first, then the least recently scanned domains by day,
+// then the domains whose DNS records changed most recently. Domains that do not fit in the
+// budget are scanned first on the next run since their last scan stays the oldest. The last
+// scans are the ones of the scanner that runs, so each scanner keeps its own order.
+func prioritizeScanDomains(scanner string, domains []*core.Record) ([]*core.Record, error) {
+	lastScanned, err := scannerStore(scanner).LastScans(app)
+	if err != nil {
+		return nil, err
+	}
+	lastChanged, err := lastRecordChangeTimes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	assigned := func(d *core.Record) bool {
+		return d.GetString("Assigned_Project") != "" && now.Sub(lastScanned[d.GetString("Name")]) > assignedScanInterval
+	}
+	scannedDay := func(d *core.Record) time.Time {
+		return lastScanned[d.GetString("Name")].Truncate(24 * time.Hour)
+	}
+
+	sorted := slices.Clone(domains)
+	slices.SortStableFunc(sorted, func(a, b *core.Record) int {
+		if assigned(a) != assigned(b) {
+			if assigned(a) {
+				return -1
+			}
+			return 1
+		}
+		if c := scannedDay(a).Compare(scannedDay(b)); c != 0 {
+			return c
+		}
+		return lastChanged[b.Id].Compare(lastChanged[a.Id])
+	})
+	return sorted, nil
+}
+
+// Time of the last DNS record change of each domain by id
+func lastRecordChangeTimes() (map[string]time.Time, error) {
+	var rows []struct {
+		Domain  string `db:"Domain"`
+		Changed string `db:"changed"`
+	}
+	err := app.DB().Select("Domain", "MAX(created) AS changed").From("Domain_Record_Changes").GroupBy("Domain").All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		changed, err := types.ParseDateTime(row.Changed)
+		if err != nil {
+			return nil, err
+		}
+		times[row.Domain] = changed.Time()
+	}
+	return times, nil
+}
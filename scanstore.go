@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lum8rjack/redcompass/health"
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/scanners/virustotal"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Store of the results of a scanner, scanners that do not register their own store return
+// results in the VirusTotal format
+func scannerStore(scanner string) providers.ResultStore {
+	if p, ok := providers.Lookup(scanner); ok && p.Store != nil {
+		return p.Store
+	}
+	return virusTotalStore{scanner: scanner}
+}
+
+// Saves the VirusTotal results of a scanner to the VirusTotal and VirusTotal_Scans collections
+type virusTotalStore struct {
+	scanner string
+}
+
+func (s virusTotalStore) SaveResults(app core.App, results []byte) (string, error) {
+	vtresults := virustotal.VT_Database{}
+	if err := json.Unmarshal(results, &vtresults); err != nil {
+		return "", err
+	}
+	return vtresults.Domain, AddVirusTotalRecord(s.scanner, vtresults)
+}
+
+// Time of the last scan of each domain in the scan history of the scanner
+func (s virusTotalStore) LastScans(app core.App) (map[string]time.Time, error) {
+	var rows []struct {
+		Domain  string `db:"Domain"`
+		Scanned string `db:"scanned"`
+	}
+	err := app.DB().Select("Domain", "MAX(Scanned) AS scanned").
+		From("VirusTotal_Scans").
+		Where(dbx.HashExp{"Scanner": s.scanner}).
+		GroupBy("Domain").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	times := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		scanned, err := types.ParseDateTime(row.Scanned)
+		if err != nil {
+			return nil, err
+		}
+		times[row.Domain] = scanned.Time()
+	}
+	return times, nil
+}
+
+// Detections and engine verdicts of the latest result of the scanner for a domain
+func (s virusTotalStore) HealthResults(app core.App, domain *core.Record) (health.Results, error) {
+	// No record is found when the scanner did not scan the domain yet
+	records, err := app.FindAllRecords("VirusTotal", dbx.HashExp{"Scanner": s.scanner, "Domain": domain.GetString("Name")})
+	if err != nil {
+		return health.Results{}, err
+	}
+
+	results := health.Results{Engines: map[string]string{}}
+	for _, record := range records {
+		engines := map[string]virustotal.VT_Analysis_Engine_Result{}
+		if err := record.UnmarshalJSONField("Last_Analysis_Results", &engines); err != nil {
+			return health.Results{}, err
+		}
+
+		results.Malicious = record.GetInt("Malicious")
+		results.Suspicious = record.GetInt("Suspicious")
+		for engine, result := range engines {
+			results.Engines[engine] = result.Category
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+const (
+	// Environment variables used to provide the key that encrypts the service settings
+	encryptionKeyEnv     = "REDCOMPASS_ENCRYPTION_KEY"
+	encryptionKeyFileEnv = "REDCOMPASS_ENCRYPTION_KEY_FILE"
+
+	// Key of the JSON object that holds the encrypted settings
+	encryptedSettingsKey = "encrypted"
+
+	// Prefix of a masked secret returned by the API
+	maskedPrefix = "****"
+)
+
+// Collections with a Settings field that holds secrets, they are encrypted and masked the same way
+var encryptedCollections = []string{"Services", "Notification_Channels"}
+
+// Get the key used to encrypt the service settings from the environment variable or the
+// file it points to. An empty key is returned if neither is set.
+func getEncryptionKey() (string, error) {
+	key := os.Getenv(encryptionKeyEnv)
+
+	if key == "" && os.Getenv(encryptionKeyFileEnv) != "" {
+		data, err := os.ReadFile(os.Getenv(encryptionKeyFileEnv))
+		if err != nil {
+			return "", err
+		}
+		key = strings.TrimSpace(string(data))
+	}
+
+	if key != "" && len(key) != 32 {
+		return "", errors.New("encryption key must be 32 characters")
+	}
+
+	return key, nil
+}
+
+// Check if the settings have been encrypted
+func isEncryptedSettings(settings string) bool {
+	var s map[string]any
+	if err := json.Unmarshal([]byte(settings), &s); err != nil {
+		return false
+	}
+
+	_, ok := s[encryptedSettingsKey].(string)
+	return ok && len(s) == 1
+}
+
+// Encrypt the settings and return them as a JSON object so they can still be stored in the Settings field
+func EncryptSettings(settings string, key string) (string, error) {
+	if isEncryptedSettings(settings) {
+		return settings, nil
+	}
+
+	cipherText, err := security.Encrypt([]byte(settings), key)
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, err := json.Marshal(map[string]string{encryptedSettingsKey: cipherText})
+	if err != nil {
+		return "", err
+	}
+
+	return string(encrypted), nil
+}
+
+// Decrypt the settings, settings that are not encrypted are returned as they are
+func DecryptSettings(settings string, key string) (string, error) {
+	if !isEncryptedSettings(settings) {
+		return settings, nil
+	}
+
+	if key == "" {
+		return "", errors.New("settings are encrypted but no encryption key is configured")
+	}
+
+	var s map[string]string
+	if err := json.Unmarshal([]byte(settings), &s); err != nil {
+		return "", err
+	}
+
+	plainText, err := security.Decrypt(s[encryptedSettingsKey], key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+// Get the decrypted settings for a service record
+func GetServiceSettings(record *core.Record) (string, error) {
+	key, err := getEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+
+	return DecryptSettings(record.GetString("Settings"), key)
+}
+
+// Check if a setting holds a secret based on its name
+func isSecretSetting(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"key", "token", "secret", "password", "webhook"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask a secret so only the last 4 characters are visible
+func maskSecret(value string) string {
+	if len(value) <= 8 {
+		return maskedPrefix
+	}
+	return maskedPrefix + value[len(value)-4:]
+}
+
+// Mask the secrets in the settings so they can be returned by the API
+func MaskSettings(settings string) (map[string]any, error) {
+	var s map[string]any
+	if err := json.Unmarshal([]byte(settings), &s); err != nil {
+		return nil, err
+	}
+
+	for k, v := range s {
+		if value, ok := v.(string); ok && value != "" && isSecretSetting(k) {
+			s[k] = maskSecret(value)
+		}
+	}
+
+	return s, nil
+}
+
+// Replace masked secrets in updated settings with the values from the existing settings
+// so saving a form that was loaded with masked secrets does not overwrite the real ones
+func mergeMaskedSettings(settings string, existing string) (string, error) {
+	var s map[string]any
+	if err := json.Unmarshal([]byte(settings), &s); err != nil {
+		return "", err
+	}
+
+	var e map[string]any
+	if err := json.Unmarshal([]byte(existing), &e); err != nil {
+		return settings, nil
+	}
+
+	for k, v := range s {
+		if value, ok := v.(string); ok && strings.HasPrefix(value, maskedPrefix) {
+			s[k] = e[k]
+		}
+	}
+
+	merged, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	return string(merged), nil
+}
+
+// Encrypt the settings of a service before it is saved
+func encryptServiceSettings(record *core.Record) error {
+	key, err := getEncryptionKey()
+	if err != nil {
+		return err
+	}
+
+	original := ""
+	if !record.IsNew() {
+		original = record.Original().GetString("Settings")
+	}
+
+	settings, err := prepareSettings(record.GetString("Settings"), original, record.IsNew(), key)
+	if err != nil {
+		return err
+	}
+
+	record.Set("Settings", settings)
+	return nil
+}
+
+// Prepare the settings sent for a record to be saved. Masked secrets sent back by the frontend
+// are replaced with the values from the original settings and the result is encrypted when a
+// key is configured. Encrypted settings are only accepted when they are unchanged, so a client
+// cannot store a value that fails to decrypt.
+func prepareSettings(settings string, original string, isNew bool, key string) (string, error) {
+	if isEncryptedSettings(settings) {
+		if !isNew && settings == original {
+			return settings, nil
+		}
+		return "", errors.New("settings must be sent as plain JSON, not encrypted")
+	}
+
+	if !isNew {
+		existing, err := DecryptSettings(original, key)
+		if err != nil {
+			return "", err
+		}
+		settings, err = mergeMaskedSettings(settings, existing)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Without a key the settings are stored as they are
+	if key == "" {
+		return settings, nil
+	}
+	return EncryptSettings(settings, key)
+}
+
+// Encrypt the settings of all services and notification channels that were saved before an
+// encryption key was configured
+func encryptAllServiceSettings() error {
+	key, err := getEncryptionKey()
+	if err != nil || key == "" {
+		return err
+	}
+
+	for _, collection := range encryptedCollections {
+		records, err := app.FindAllRecords(collection)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if isEncryptedSettings(record.GetString("Settings")) {
+				continue
+			}
+
+			// The save hook encrypts the settings
+			if err := app.Save(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Re-encrypt the settings of all services and notification channels with a new key
+func rotateEncryptionKey(oldKey string, newKey string) (int, error) {
+	if len(newKey) != 32 {
+		return 0, errors.New("new encryption key must be 32 characters")
+	}
+
+	rotated := 0
+	err := app.RunInTransaction(func(txApp core.App) error {
+		for _, collection := range encryptedCollections {
+			records, err := txApp.FindAllRecords(collection)
+			if err != nil {
+				return err
+			}
+
+			for _, record := range records {
+				encrypted, err := reencryptSettings(record.GetString("Settings"), oldKey, newKey)
+				if err != nil {
+					return errors.New("failed to re-encrypt the settings for " + collection + " record " + record.Id + ": " + err.Error())
+				}
+
+				// The settings are updated directly since the save hook only accepts plain settings
+				// and would encrypt them with the old key
+				_, err = txApp.DB().Update(collection,
+					dbx.Params{"Settings": encrypted},
+					dbx.HashExp{"id": record.Id},
+				).Execute()
+				if err != nil {
+					return err
+				}
+				rotated++
+			}
+		}
+
+		return nil
+	})
+
+	return rotated, err
+}
+
+// Decrypt the settings with the old key and encrypt them with the new key, settings that are
+// not encrypted yet are encrypted with the new key
+func reencryptSettings(settings string, oldKey string, newKey string) (string, error) {
+	plain, err := DecryptSettings(settings, oldKey)
+	if err != nil {
+		return "", err
+	}
+
+	return EncryptSettings(plain, newKey)
+}
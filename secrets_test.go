@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const (
+	testKey      = "0123456789abcdef0123456789abcdef"
+	testOtherKey = "fedcba9876543210fedcba9876543210"
+)
+
+func TestEncryptSettings(t *testing.T) {
+	encrypted, err := EncryptSettings(`{"apiKey":"secret"}`, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedSettings(encrypted) {
+		t.Fatalf("expected encrypted settings, got %s", encrypted)
+	}
+
+	again, err := EncryptSettings(encrypted, testKey)
+	if err != nil || again != encrypted {
+		t.Fatalf("expected encrypted settings to be returned as they are, got %s, %v", again, err)
+	}
+
+	tests := []struct {
+		name     string
+		settings string
+		key      string
+		want     string
+		wantErr  bool
+	}{
+		{"encrypted", encrypted, testKey, `{"apiKey":"secret"}`, false},
+		{"plain", `{"apiKey":"secret"}`, testKey, `{"apiKey":"secret"}`, false},
+		{"plain without key", `{"apiKey":"secret"}`, "", `{"apiKey":"secret"}`, false},
+		{"encrypted without key", encrypted, "", "", true},
+		{"wrong key", encrypted, testOtherKey, "", true},
+		{"invalid ciphertext", `{"encrypted":"garbage"}`, testKey, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptSettings(tt.settings, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMaskSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		want     map[string]any
+		wantErr  bool
+	}{
+		{"long secret", `{"apiKey":"abcdefghijkl"}`, map[string]any{"apiKey": "****ijkl"}, false},
+		{"short secret", `{"apiToken":"abcd"}`, map[string]any{"apiToken": "****"}, false},
+		{"empty secret", `{"secretApiKey":""}`, map[string]any{"secretApiKey": ""}, false},
+		{"webhook", `{"webhookUrl":"https://hooks.example.com/abc123"}`, map[string]any{"webhookUrl": "****c123"}, false},
+		{"not secret", `{"username":"admin","baseUrl":"https://api.example.com"}`, map[string]any{"username": "admin", "baseUrl": "https://api.example.com"}, false},
+		{"not a string", `{"apiKey":123}`, map[string]any{"apiKey": float64(123)}, false},
+		{"invalid", `not json`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MaskSettings(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !jsonEqual(t, got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeMaskedSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		existing string
+		want     string
+		wantErr  bool
+	}{
+		{"masked", `{"apiKey":"****ijkl","username":"new"}`, `{"apiKey":"abcdefghijkl","username":"old"}`, `{"apiKey":"abcdefghijkl","username":"new"}`, false},
+		{"changed", `{"apiKey":"newkey"}`, `{"apiKey":"abcdefghijkl"}`, `{"apiKey":"newkey"}`, false},
+		{"masked without existing value", `{"apiKey":"****"}`, `{}`, `{"apiKey":null}`, false},
+		{"invalid existing", `{"apiKey":"****"}`, ``, `{"apiKey":"****"}`, false},
+		{"invalid settings", `not json`, `{}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeMaskedSettings(tt.settings, tt.existing)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrepareSettings(t *testing.T) {
+	existing, err := EncryptSettings(`{"apiKey":"abcdefghijkl"}`, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := EncryptSettings(`{"apiKey":"other"}`, testOtherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		settings string
+		original string
+		isNew    bool
+		key      string
+		want     string
+		wantErr  bool
+	}{
+		{"new", `{"apiKey":"abcdefghijkl"}`, "", true, testKey, `{"apiKey":"abcdefghijkl"}`, false},
+		{"new without key", `{"apiKey":"abcdefghijkl"}`, "", true, "", `{"apiKey":"abcdefghijkl"}`, false},
+		{"unchanged", existing, existing, false, testKey, `{"apiKey":"abcdefghijkl"}`, false},
+		{"masked secret", `{"apiKey":"****ijkl","username":"admin"}`, existing, false, testKey, `{"apiKey":"abcdefghijkl","username":"admin"}`, false},
+		{"changed secret", `{"apiKey":"newkey"}`, existing, false, testKey, `{"apiKey":"newkey"}`, false},
+		{"changed ciphertext", forged, existing, false, testKey, "", true},
+		{"ciphertext on a new record", `{"encrypted":"garbage"}`, "", true, testKey, "", true},
+		{"original cannot be decrypted", `{"apiKey":"****ijkl"}`, existing, false, testOtherKey, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prepareSettings(tt.settings, tt.original, tt.isNew, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if tt.key != "" {
+				if !isEncryptedSettings(got) {
+					t.Fatalf("expected encrypted settings, got %s", got)
+				}
+				if got, err = DecryptSettings(got, tt.key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !jsonEqual(t, got, tt.want) {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReencryptSettings(t *testing.T) {
+	encrypted, err := EncryptSettings(`{"apiKey":"abcdefghijkl"}`, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		settings string
+		oldKey   string
+		newKey   string
+		wantErr  bool
+	}{
+		{"encrypted", encrypted, testKey, testOtherKey, false},
+		{"plain", `{"apiKey":"abcdefghijkl"}`, testKey, testOtherKey, false},
+		{"wrong old key", encrypted, testOtherKey, testKey, true},
+		{"invalid new key", encrypted, testKey, "short", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reencryptSettings(tt.settings, tt.oldKey, tt.newKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if _, err := DecryptSettings(got, tt.oldKey); err == nil {
+				t.Fatal("expected the old key to no longer decrypt the settings")
+			}
+			plain, err := DecryptSettings(got, tt.newKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, plain, `{"apiKey":"abcdefghijkl"}`) {
+				t.Fatalf("expected the settings to be kept, got %s", plain)
+			}
+		})
+	}
+}
+
+// Compare two values by their JSON encoding, strings are decoded first
+func jsonEqual(t *testing.T, got any, want any) bool {
+	t.Helper()
+
+	normalize := func(v any) string {
+		if s, ok := v.(string); ok {
+			var decoded any
+			if err := json.Unmarshal([]byte(s), &decoded); err == nil {
+				v = decoded
+			}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	return normalize(got) == normalize(want)
+}
@@ -0,0 +1,551 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lum8rjack/redcompass/providers"
+	"github.com/lum8rjack/redcompass/services/types"
+	"golang.org/x/time/rate"
+)
+
+// Default URL of the API, can be changed in the settings to use a proxy or a test server
+const defaultBaseURL = "https://api.cloudflare.com/client/v4"
+
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     "Cloudflare",
+		Kind:     providers.KindRegistrar,
+		Settings: settingsSchema,
+		NewService: func(settings string) (types.Service, error) {
+			return NewClient(settings)
+		},
+	})
+}
+
+type Settings struct {
+	ApiToken  string `json:"apiToken"`
+	AccountId string `json:"accountId"`
+	BaseURL   string `json:"baseUrl"`
+}
+
+type Client struct {
+	client           *http.Client
+	baseURL          string
+	apiToken         string
+	accountId        string
+	zones            map[string]Zone
+	perMinuteLimiter *rate.Limiter
+}
+
+func NewClient(settings string) (*Client, error) {
+	var cloudflareSettings Settings
+	err := json.Unmarshal([]byte(settings), &cloudflareSettings)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the settings are valid
+	if cloudflareSettings.ApiToken == "" {
+		return nil, errors.New("invalid settings")
+	}
+
+	baseURL := defaultBaseURL
+	if cloudflareSettings.BaseURL != "" {
+		baseURL = strings.TrimSuffix(cloudflareSettings.BaseURL, "/")
+	}
+
+	// Cloudflare limit: 1200 requests per 5 minutes across the whole user
+	return &Client{
+		client:           providers.NewHTTPClient("Cloudflare", providers.ClientOptions{}),
+		baseURL:          baseURL,
+		apiToken:         cloudflareSettings.ApiToken,
+		accountId:        cloudflareSettings.AccountId,
+		zones:            make(map[string]Zone),
+		perMinuteLimiter: rate.NewLimiter(rate.Every(5*time.Minute/1200), 1),
+	}, nil
+}
+
+// GetName returns the name of the service
+func (c *Client) GetName() string {
+	return "Cloudflare"
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+}
+
+type Response struct {
+	Success    bool            `json:"success"`
+	Errors     []ResponseError `json:"errors"`
+	Result     json.RawMessage `json:"result"`
+	ResultInfo *ResultInfo     `json:"result_info"`
+}
+
+type Account struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Zone struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Status      string   `json:"status"`
+	Paused      bool     `json:"paused"`
+	NameServers []string `json:"name_servers"`
+}
+
+type RegistrarDomain struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	AutoRenew bool   `json:"auto_renew"`
+	Locked    bool   `json:"locked"`
+	Privacy   bool   `json:"privacy"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type DNSRecord struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority"`
+	Proxied  bool   `json:"proxied"`
+}
+
+// GetDomains returns a list of all domains registered with Cloudflare Registrar
+func (c *Client) GetDomains(ctx context.Context) ([]types.Domain, error) {
+	var domains []types.Domain
+
+	// Load the zones first so we can tell which domains are using Cloudflare DNS
+	if err := c.loadZones(ctx); err != nil {
+		return domains, err
+	}
+
+	accountIds, err := c.getAccountIds(ctx)
+	if err != nil {
+		return domains, err
+	}
+
+	for _, accountId := range accountIds {
+		var registrarDomains []RegistrarDomain
+		path := fmt.Sprintf("/accounts/%s/registrar/domains", accountId)
+		err := c.getAllPages(ctx, path, url.Values{}, func(result json.RawMessage) error {
+			var page []RegistrarDomain
+			if err := json.Unmarshal(result, &page); err != nil {
+				return err
+			}
+			registrarDomains = append(registrarDomains, page...)
+			return nil
+		})
+		if err != nil {
+			return domains, err
+		}
+
+		// Locked is the registrar transfer lock, which is on by default and does not stop
+		// the records from being synced or the domain from being scanned
+		for _, x := range registrarDomains {
+			newDomain := types.Domain{
+				Name:       x.Name,
+				AutoRenew:  x.AutoRenew,
+				IsLocked:   false,
+				WhoIsGuard: x.Privacy,
+			}
+
+			if x.CreatedAt != "" {
+				newDomain.Created, err = time.Parse(time.RFC3339, x.CreatedAt)
+				if err != nil {
+					return domains, err
+				}
+			}
+
+			if x.ExpiresAt != "" {
+				newDomain.Expires, err = time.Parse(time.RFC3339, x.ExpiresAt)
+				if err != nil {
+					return domains, err
+				}
+			}
+
+			newDomain.IsExpired = false
+			if newDomain.Expires.Before(time.Now()) {
+				newDomain.IsExpired = true
+			}
+
+			newDomain.IsOurDNS = false
+			if zone, ok := c.zones[x.Name]; ok {
+				newDomain.IsOurDNS = isUsingCloudflareNameservers(zone)
+			}
+
+			domains = append(domains, newDomain)
+		}
+	}
+
+	return domains, nil
+}
+
+// GetDomainRecords returns a list of all records for a domain
+func (c *Client) GetDomainRecords(ctx context.Context, domain string) ([]types.Record, error) {
+	var records []types.Record
+
+	zone, err := c.getZone(ctx, domain)
+	if err != nil {
+		return records, err
+	}
+
+	path := fmt.Sprintf("/zones/%s/dns_records", zone.ID)
+	err = c.getAllPages(ctx, path, url.Values{}, func(result json.RawMessage) error {
+		var page []DNSRecord
+		if err := json.Unmarshal(result, &page); err != nil {
+			return err
+		}
+
+		for _, x := range page {
+			r := types.Record{
+				Domain:  domain,
+				HostId:  x.ID,
+				Name:    x.Name,
+				Type:    x.Type,
+				Address: x.Content,
+				Proxied: x.Proxied,
+			}
+
+			if x.Priority != nil {
+				r.Priority = strconv.Itoa(*x.Priority)
+			}
+
+			// A TTL of 1 means Cloudflare manages it automatically
+			if x.TTL == 1 {
+				r.TTL = "auto"
+			} else if x.TTL > 0 {
+				r.TTL = strconv.Itoa(x.TTL)
+			}
+
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return records, err
+	}
+
+	return records, nil
+}
+
+type DNSRecordRequest struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority,omitempty"`
+	Proxied  bool   `json:"proxied"`
+}
+
+// CreateRecord adds a record to a domain
+func (c *Client) CreateRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	zone, err := c.getZone(ctx, record.Domain)
+	if err != nil {
+		return record, err
+	}
+
+	requestBody, err := newDNSRecordRequest(record)
+	if err != nil {
+		return record, err
+	}
+
+	var result DNSRecord
+	path := fmt.Sprintf("/zones/%s/dns_records", zone.ID)
+	if _, err := c.do(ctx, "POST", path, nil, requestBody, &result); err != nil {
+		return record, err
+	}
+
+	record.HostId = result.ID
+	record.Name = result.Name
+	return record, nil
+}
+
+// UpdateRecord replaces the record with the matching HostId
+func (c *Client) UpdateRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	if record.HostId == "" {
+		return record, errors.New("record id is empty")
+	}
+
+	zone, err := c.getZone(ctx, record.Domain)
+	if err != nil {
+		return record, err
+	}
+
+	requestBody, err := newDNSRecordRequest(record)
+	if err != nil {
+		return record, err
+	}
+
+	var result DNSRecord
+	path := fmt.Sprintf("/zones/%s/dns_records/%s", zone.ID, record.HostId)
+	if _, err := c.do(ctx, "PUT", path, nil, requestBody, &result); err != nil {
+		return record, err
+	}
+
+	record.Name = result.Name
+	return record, nil
+}
+
+// DeleteRecord deletes the record with the matching HostId
+func (c *Client) DeleteRecord(ctx context.Context, record types.Record) error {
+	if record.HostId == "" {
+		return errors.New("record id is empty")
+	}
+
+	zone, err := c.getZone(ctx, record.Domain)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/zones/%s/dns_records/%s", zone.ID, record.HostId)
+	_, err = c.do(ctx, "DELETE", path, nil, nil, nil)
+	return err
+}
+
+// Create the request body to create or update a record
+func newDNSRecordRequest(record types.Record) (DNSRecordRequest, error) {
+	request := DNSRecordRequest{
+		Name:    record.Name,
+		Type:    record.Type,
+		Content: record.Address,
+		TTL:     1,
+		Proxied: record.Proxied,
+	}
+
+	if record.TTL != "" && record.TTL != "auto" {
+		ttl, err := strconv.Atoi(record.TTL)
+		if err != nil {
+			return request, fmt.Errorf("invalid TTL: %s", record.TTL)
+		}
+		request.TTL = ttl
+	}
+
+	if record.Priority != "" {
+		priority, err := strconv.Atoi(record.Priority)
+		if err != nil {
+			return request, fmt.Errorf("invalid priority: %s", record.Priority)
+		}
+		request.Priority = &priority
+	}
+
+	return request, nil
+}
+
+// Get the account IDs to list registrar domains for. If an account ID was not
+// provided in the settings, every account the token has access to is used.
+func (c *Client) getAccountIds(ctx context.Context) ([]string, error) {
+	if c.accountId != "" {
+		return []string{c.accountId}, nil
+	}
+
+	var accountIds []string
+	err := c.getAllPages(ctx, "/accounts", url.Values{}, func(result json.RawMessage) error {
+		var page []Account
+		if err := json.Unmarshal(result, &page); err != nil {
+			return err
+		}
+		for _, x := range page {
+			accountIds = append(accountIds, x.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accountIds) == 0 {
+		return nil, errors.New("no accounts found for the API token")
+	}
+
+	return accountIds, nil
+}
+
+// Load all zones the token has access to
+func (c *Client) loadZones(ctx context.Context) error {
+	query := url.Values{}
+	if c.accountId != "" {
+		query.Set("account.id", c.accountId)
+	}
+
+	return c.getAllPages(ctx, "/zones", query, func(result json.RawMessage) error {
+		var page []Zone
+		if err := json.Unmarshal(result, &page); err != nil {
+			return err
+		}
+		for _, x := range page {
+			c.zones[x.Name] = x
+		}
+		return nil
+	})
+}
+
+// Get the zone for a domain, looking it up if it was not already loaded
+func (c *Client) getZone(ctx context.Context, domain string) (Zone, error) {
+	if zone, ok := c.zones[domain]; ok {
+		return zone, nil
+	}
+
+	query := url.Values{}
+	query.Set("name", domain)
+	if c.accountId != "" {
+		query.Set("account.id", c.accountId)
+	}
+
+	var zones []Zone
+	if _, err := c.get(ctx, "/zones", query, &zones); err != nil {
+		return Zone{}, err
+	}
+
+	if len(zones) == 0 {
+		return Zone{}, providers.NewError("Cloudflare", providers.ErrNotFound, "zone not found for "+domain)
+	}
+
+	c.zones[domain] = zones[0]
+	return zones[0], nil
+}
+
+// Check if the zone is active and delegated to Cloudflare nameservers
+func isUsingCloudflareNameservers(zone Zone) bool {
+	if zone.Status != "active" || len(zone.NameServers) == 0 {
+		return false
+	}
+
+	return strings.HasSuffix(zone.NameServers[0], ".ns.cloudflare.com")
+}
+
+// Request every page of a list endpoint and pass each result to the handler
+func (c *Client) getAllPages(ctx context.Context, path string, query url.Values, handler func(json.RawMessage) error) error {
+	page := 1
+	for {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", "50")
+
+		var result json.RawMessage
+		info, err := c.get(ctx, path, query, &result)
+		if err != nil {
+			return err
+		}
+
+		if err := handler(result); err != nil {
+			return err
+		}
+
+		if info == nil || page >= info.TotalPages {
+			return nil
+		}
+		page++
+	}
+}
+
+// Send a GET request to the API and decode the result
+func (c *Client) get(ctx context.Context, path string, query url.Values, result any) (*ResultInfo, error) {
+	return c.do(ctx, "GET", path, query, nil, result)
+}
+
+// Send a request to the API and decode the result
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, result any) (*ResultInfo, error) {
+	// Wait for rate limiter
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		requestBodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(requestBodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("accept", "application/json")
+	req.Header.Add("authorization", "Bearer "+c.apiToken)
+	if body != nil {
+		req.Header.Add("content-type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, providers.FromRequestError("Cloudflare", err)
+	}
+	defer resp.Body.Close()
+
+	var response Response
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, providers.FromResponse("Cloudflare", resp, "")
+		}
+		return nil, err
+	}
+
+	if !response.Success {
+		message := ""
+		if len(response.Errors) > 0 {
+			message = fmt.Sprintf("%d: %s", response.Errors[0].Code, response.Errors[0].Message)
+		}
+		return nil, providers.FromResponse("Cloudflare", resp, message)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return response.ResultInfo, nil
+}
+
+// Wait for the rate limiters
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	// Stop right away if the job was cancelled instead of reporting a rate limit error
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	if err := c.perMinuteLimiter.Wait(ctx); err != nil {
+		return errors.New("failed to wait for per minute rate limit")
+	}
+
+	return nil
+}
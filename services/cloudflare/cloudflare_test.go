@@ -0,0 +1,48 @@
+package cloudflare
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lum8rjack/redcompass/providers/providertest"
+	"github.com/lum8rjack/redcompass/services/types"
+	"golang.org/x/time/rate"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunServiceTests(t, providertest.ServiceSuite{
+		Handler:  providertest.CloudflareHandler,
+		PageSize: 50,
+		New: func(t *testing.T, baseURL string) types.Service {
+			c, err := NewClient(`{"apiToken":"test-token","baseUrl":"` + baseURL + `"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.perMinuteLimiter = rate.NewLimiter(rate.Inf, 1)
+			return c
+		},
+	})
+}
+
+func TestTransferLock(t *testing.T) {
+	registrar := providertest.NewRegistrar()
+	registrar.AddDomain(types.Domain{Name: "locked.com", IsLocked: true, Expires: time.Now().AddDate(1, 0, 0)})
+	server := httptest.NewServer(providertest.CloudflareHandler(registrar))
+	t.Cleanup(server.Close)
+
+	c, err := NewClient(`{"apiToken":"test-token","baseUrl":"` + server.URL + `"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.perMinuteLimiter = rate.NewLimiter(rate.Inf, 1)
+
+	domains, err := c.GetDomains(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(domains) != 1 || domains[0].IsLocked {
+		t.Fatalf("expected the transfer lock to be ignored, got %+v", domains)
+	}
+}
@@ -2,25 +2,66 @@ package namecheap
 
 import (
 	"context"
+	_ "embed"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lum8rjack/redcompass/providers"
 	"github.com/lum8rjack/redcompass/services/types"
 	nc "github.com/namecheap/go-namecheap-sdk/v2/namecheap"
 	"golang.org/x/time/rate"
 )
 
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     "Namecheap",
+		Kind:     providers.KindRegistrar,
+		Settings: settingsSchema,
+		NewService: func(settings string) (types.Service, error) {
+			return NewClient(settings)
+		},
+	})
+}
+
 type Settings struct {
 	ApiKey   string `json:"apiKey"`
 	Username string `json:"username"`
 	IP       string `json:"ipAddress"`
+	Sandbox  bool   `json:"sandbox"`
+	BaseURL  string `json:"baseUrl"`
+}
+
+// Maximum page size allowed by the domains.getList API
+const pageSize = 100
+
+// Namecheap error numbers and the kind of error they are
+var errorKinds = map[string]error{
+	"1011102": providers.ErrAuth,        // API key is invalid or API access has not been enabled
+	"1011150": providers.ErrAuth,        // Request IP is not whitelisted
+	"500000":  providers.ErrRateLimited, // Too many requests
+	"2019166": providers.ErrNotFound,    // Domain not found
+	"2016166": providers.ErrNotFound,    // Domain is not associated with the account
+	"3050900": providers.ErrTransient,   // Unknown error from the registry
+	"5050900": providers.ErrTransient,   // Unhandled exception
 }
 
 type Client struct {
 	client           *nc.Client
+	httpClient       *http.Client
+	totalDomains     int
 	perMinuteLimiter *rate.Limiter
 	perHourLimiter   *rate.Limiter
 	perDayLimiter    *rate.Limiter
@@ -48,12 +89,19 @@ func NewClient(settings string) (*Client, error) {
 		ApiUser:    namecheapSettings.Username,
 		ApiKey:     namecheapSettings.ApiKey,
 		ClientIp:   namecheapSettings.IP,
-		UseSandbox: false,
+		UseSandbox: namecheapSettings.Sandbox,
 	})
 
+	// The SDK uses the production or sandbox URL, a custom URL can be used for a proxy or a test server
+	if namecheapSettings.BaseURL != "" {
+		c.BaseURL = namecheapSettings.BaseURL
+	}
+
+	// Namecheap returns a 405 when requests are throttled, the shared client retries it.
 	// Namecheap limit: 50/min, 700/hour, and 8000/day across the whole key
 	return &Client{
 		client:           c,
+		httpClient:       providers.NewHTTPClient("Namecheap", providers.ClientOptions{RetryStatuses: []int{http.StatusMethodNotAllowed}}),
 		perMinuteLimiter: rate.NewLimiter(rate.Every(time.Minute/50), 1),
 		perHourLimiter:   rate.NewLimiter(rate.Every(time.Hour/700), 650),
 		perDayLimiter:    rate.NewLimiter(rate.Every(24*time.Hour/8000), 7900),
@@ -66,89 +114,113 @@ func (c *Client) GetName() string {
 }
 
 // GetDomains returns a list of all domains for the user
-func (c *Client) GetDomains() ([]types.Domain, error) {
+func (c *Client) GetDomains(ctx context.Context) ([]types.Domain, error) {
 	var domains []types.Domain
 
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(); err != nil {
-		return nil, err
-	}
-
-	ncresp, err := c.client.Domains.GetList(&nc.DomainsGetListArgs{
-		ListType: nc.String("ALL"),
-		PageSize: nc.Int(100),
-	})
-	if err != nil {
-		return domains, err
-	}
-
-	// If the Domains is nil, no domains were found
-	if ncresp.Domains == nil {
-		return domains, nil
-	}
-
-	for _, x := range *ncresp.Domains {
-		newDomain := types.Domain{}
-
-		// Check the pointers for nil values
-		if x.Name != nil {
-			newDomain.Name = *x.Name
+	// Request every page, the API returns at most 100 domains per page
+	c.totalDomains = 0
+	page := 1
+	for {
+		var response nc.DomainsGetListResponse
+		err := c.do(providers.Idempotent(ctx), map[string]string{
+			"Command":  "namecheap.domains.getList",
+			"ListType": "ALL",
+			"Page":     strconv.Itoa(page),
+			"PageSize": strconv.Itoa(pageSize),
+		}, &response)
+		if err != nil {
+			return domains, err
 		}
 
-		if x.Created != nil {
-			newDomain.Created = x.Created.Time
+		// If the CommandResponse is nil, no domains were returned
+		ncresp := response.CommandResponse
+		if ncresp == nil {
+			break
 		}
 
-		if x.Expires != nil {
-			newDomain.Expires = x.Expires.Time
+		if ncresp.Paging != nil && ncresp.Paging.TotalItems != nil {
+			c.totalDomains = *ncresp.Paging.TotalItems
 		}
 
-		if x.IsExpired != nil {
-			newDomain.IsExpired = *x.IsExpired
+		// If the Domains is nil, no more domains were found
+		if ncresp.Domains == nil || len(*ncresp.Domains) == 0 {
+			break
 		}
 
-		if x.IsLocked != nil {
-			newDomain.IsLocked = *x.IsLocked
+		for _, x := range *ncresp.Domains {
+			domains = append(domains, convertDomain(x))
 		}
 
-		if x.AutoRenew != nil {
-			newDomain.AutoRenew = *x.AutoRenew
+		// Stop once we have every domain the API reported or the last page was not full
+		if len(*ncresp.Domains) < pageSize || (c.totalDomains > 0 && len(domains) >= c.totalDomains) {
+			break
 		}
+		page++
+	}
 
-		if x.WhoisGuard != nil {
-			newDomain.WhoIsGuard = *x.WhoisGuard == "ENABLED"
-		}
+	return domains, nil
+}
 
-		if x.IsOurDNS != nil {
-			newDomain.IsOurDNS = *x.IsOurDNS
-		}
+// GetTotalDomains returns the total number of domains reported by the API during the last GetDomains call
+func (c *Client) GetTotalDomains() int {
+	return c.totalDomains
+}
+
+// Convert a Namecheap domain to a types.Domain
+func convertDomain(x nc.Domain) types.Domain {
+	newDomain := types.Domain{}
 
-		domains = append(domains, newDomain)
+	// Check the pointers for nil values
+	if x.Name != nil {
+		newDomain.Name = *x.Name
 	}
 
-	return domains, nil
+	if x.Created != nil {
+		newDomain.Created = x.Created.Time
+	}
+
+	if x.Expires != nil {
+		newDomain.Expires = x.Expires.Time
+	}
+
+	if x.IsExpired != nil {
+		newDomain.IsExpired = *x.IsExpired
+	}
+
+	if x.IsLocked != nil {
+		newDomain.IsLocked = *x.IsLocked
+	}
+
+	if x.AutoRenew != nil {
+		newDomain.AutoRenew = *x.AutoRenew
+	}
+
+	if x.WhoisGuard != nil {
+		newDomain.WhoIsGuard = *x.WhoisGuard == "ENABLED"
+	}
+
+	if x.IsOurDNS != nil {
+		newDomain.IsOurDNS = *x.IsOurDNS
+	}
+
+	return newDomain
 }
 
 // GetDomainRecords returns a list of all records for a domain
-func (c *Client) GetDomainRecords(domain string) ([]types.Record, error) {
+func (c *Client) GetDomainRecords(ctx context.Context, domain string) ([]types.Record, error) {
 	var records []types.Record
 
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(); err != nil {
-		return nil, err
-	}
-
-	ncresp, err := c.client.DomainsDNS.GetHosts(domain)
+	result, err := c.getHosts(ctx, domain)
 	if err != nil {
 		return records, err
 	}
 
 	// If the Hosts is nil, no records were found
-	if ncresp.DomainDNSGetHostsResult.Hosts == nil {
+	if result.Hosts == nil {
 		return records, nil
 	}
 
-	for _, x := range *ncresp.DomainDNSGetHostsResult.Hosts {
+	for _, x := range *result.Hosts {
 		r := types.Record{
 			Domain: domain,
 		}
@@ -170,15 +242,306 @@ func (c *Client) GetDomainRecords(domain string) ([]types.Record, error) {
 			r.HostId = strconv.Itoa(*x.HostId)
 		}
 
+		if x.TTL != nil {
+			r.TTL = strconv.Itoa(*x.TTL)
+		}
+
+		if x.MXPref != nil && r.Type == nc.RecordTypeMX {
+			r.Priority = strconv.Itoa(*x.MXPref)
+		}
+
 		records = append(records, r)
 	}
 
 	return records, nil
 }
 
+// CreateRecord adds a record to a domain. Namecheap replaces the full host list on
+// every change so the existing hosts are retrieved and sent back with the new record.
+func (c *Client) CreateRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	err := c.setHosts(ctx, record.Domain, func(hosts []host) ([]host, error) {
+		newHost, err := convertRecord(record)
+		if err != nil {
+			return hosts, err
+		}
+		return append(hosts, host{record: newHost}), nil
+	})
+	if err != nil {
+		return record, err
+	}
+
+	return c.findRecord(ctx, record)
+}
+
+// UpdateRecord replaces the record with the matching HostId
+func (c *Client) UpdateRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	err := c.setHosts(ctx, record.Domain, func(hosts []host) ([]host, error) {
+		for i, h := range hosts {
+			if h.hostId != record.HostId {
+				continue
+			}
+			newHost, err := convertRecord(record)
+			if err != nil {
+				return hosts, err
+			}
+			hosts[i].record = newHost
+			return hosts, nil
+		}
+		return hosts, fmt.Errorf("record %s not found", record.HostId)
+	})
+	if err != nil {
+		return record, err
+	}
+
+	return c.findRecord(ctx, record)
+}
+
+// DeleteRecord removes the record with the matching HostId
+func (c *Client) DeleteRecord(ctx context.Context, record types.Record) error {
+	return c.setHosts(ctx, record.Domain, func(hosts []host) ([]host, error) {
+		for i, h := range hosts {
+			if h.hostId == record.HostId {
+				return append(hosts[:i], hosts[i+1:]...), nil
+			}
+		}
+		return hosts, fmt.Errorf("record %s not found", record.HostId)
+	})
+}
+
+// A host from getHosts along with its HostId
+type host struct {
+	hostId string
+	record nc.DomainsDNSHostRecord
+}
+
+// Get the current hosts for a domain, modify them and set the full list
+func (c *Client) setHosts(ctx context.Context, domain string, modify func([]host) ([]host, error)) error {
+	result, err := c.getHosts(ctx, domain)
+	if err != nil {
+		return err
+	}
+
+	var hosts []host
+	if result.Hosts != nil {
+		for _, x := range *result.Hosts {
+			h := host{
+				record: nc.DomainsDNSHostRecord{
+					HostName:   x.Name,
+					RecordType: x.Type,
+					Address:    x.Address,
+					TTL:        x.TTL,
+				},
+			}
+
+			if x.HostId != nil {
+				h.hostId = strconv.Itoa(*x.HostId)
+			}
+
+			if x.MXPref != nil && x.Type != nil && *x.Type == nc.RecordTypeMX {
+				h.record.MXPref = nc.UInt8(uint8(*x.MXPref))
+			}
+
+			hosts = append(hosts, h)
+		}
+	}
+
+	hosts, err = modify(hosts)
+	if err != nil {
+		return err
+	}
+
+	parsedDomain, err := nc.ParseDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"Command": "namecheap.domains.dns.setHosts",
+		"SLD":     parsedDomain.SLD,
+		"TLD":     parsedDomain.TLD,
+	}
+
+	mxRecords := 0
+	for i, h := range hosts {
+		if h.record.RecordType == nil || h.record.HostName == nil || h.record.Address == nil {
+			return fmt.Errorf("host %d is missing the name, type or address", i+1)
+		}
+
+		n := strconv.Itoa(i + 1)
+		params["HostName"+n] = *h.record.HostName
+		params["RecordType"+n] = *h.record.RecordType
+		params["Address"+n] = *h.record.Address
+		if h.record.TTL != nil {
+			params["TTL"+n] = strconv.Itoa(*h.record.TTL)
+		}
+		if h.record.MXPref != nil {
+			params["MXPref"+n] = strconv.Itoa(int(*h.record.MXPref))
+		}
+
+		if *h.record.RecordType == nc.RecordTypeMX {
+			mxRecords++
+		}
+	}
+
+	// MX records are only accepted when the email type is MX and the
+	// MX email type requires at least one MX record
+	emailType := ""
+	if result.EmailType != nil {
+		emailType = *result.EmailType
+	}
+	if mxRecords > 0 {
+		emailType = nc.EmailTypeMX
+	} else if emailType == nc.EmailTypeMX {
+		emailType = nc.EmailTypeNone
+	}
+	if emailType != "" {
+		params["EmailType"] = emailType
+	}
+
+	// Setting the same hosts again gives the same result so the request can be retried
+	var response nc.DomainsDNSSetHostsResponse
+	return c.do(providers.Idempotent(ctx), params, &response)
+}
+
+// Get the hosts and email type for a domain
+func (c *Client) getHosts(ctx context.Context, domain string) (*nc.DomainDNSGetHostsResult, error) {
+	parsedDomain, err := nc.ParseDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var response nc.DomainsDNSGetHostsResponse
+	err = c.do(providers.Idempotent(ctx), map[string]string{
+		"Command": "namecheap.domains.dns.getHosts",
+		"SLD":     parsedDomain.SLD,
+		"TLD":     parsedDomain.TLD,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.CommandResponse == nil || response.CommandResponse.DomainDNSGetHostsResult == nil {
+		return &nc.DomainDNSGetHostsResult{}, nil
+	}
+
+	return response.CommandResponse.DomainDNSGetHostsResult, nil
+}
+
+// Find a record after setHosts since Namecheap assigns new HostIds to every host
+func (c *Client) findRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	records, err := c.GetDomainRecords(ctx, record.Domain)
+	if err != nil {
+		return record, err
+	}
+
+	for _, r := range records {
+		if r.Name == record.Name && r.Type == record.Type && r.Address == record.Address {
+			return r, nil
+		}
+	}
+
+	return record, errors.New("record not found after update")
+}
+
+// Convert a types.Record to a Namecheap host record
+func convertRecord(record types.Record) (nc.DomainsDNSHostRecord, error) {
+	h := nc.DomainsDNSHostRecord{
+		HostName:   nc.String(record.Name),
+		RecordType: nc.String(record.Type),
+		Address:    nc.String(record.Address),
+	}
+
+	if record.TTL != "" {
+		ttl, err := strconv.Atoi(record.TTL)
+		if err != nil {
+			return h, fmt.Errorf("invalid TTL: %s", record.TTL)
+		}
+		h.TTL = nc.Int(ttl)
+	}
+
+	if record.Type == nc.RecordTypeMX {
+		priority := 10
+		if record.Priority != "" {
+			p, err := strconv.Atoi(record.Priority)
+			if err != nil || p < 0 || p > 255 {
+				return h, fmt.Errorf("invalid priority: %s", record.Priority)
+			}
+			priority = p
+		}
+		h.MXPref = nc.UInt8(uint8(priority))
+	}
+
+	return h, nil
+}
+
+// Errors returned in the body of every Namecheap API response
+type apiResponse struct {
+	Errors []struct {
+		Message string `xml:",chardata"`
+		Number  string `xml:"Number,attr"`
+	} `xml:"Errors>Error"`
+}
+
+// Send a command to the API using the SDK to build the authenticated request and decode the
+// XML response. The request is sent with the shared client so it gets the same timeouts,
+// retries and metrics as the other providers.
+func (c *Client) do(ctx context.Context, params map[string]string, response any) error {
+	// Wait for rate limiter
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return err
+	}
+
+	req, err := c.client.NewRequest(params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return providers.FromRequestError("Namecheap", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		return providers.RateLimited("Namecheap", providers.ParseRetryAfter(resp.Header.Get("Retry-After")), resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return providers.FromResponse("Namecheap", resp, "")
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return providers.FromRequestError("Namecheap", err)
+	}
+
+	var status apiResponse
+	if err := xml.Unmarshal(body, &status); err != nil {
+		return err
+	}
+	if len(status.Errors) > 0 {
+		return apiError(status.Errors[0].Number, status.Errors[0].Message)
+	}
+
+	return xml.Unmarshal(body, response)
+}
+
+// Classify an error returned by the API using the Namecheap error number
+func apiError(number string, message string) error {
+	message = fmt.Sprintf("%s (%s)", strings.TrimSpace(message), number)
+	if kind, ok := errorKinds[number]; ok {
+		return providers.NewError("Namecheap", kind, message)
+	}
+	return errors.New(message)
+}
+
 // Wait for the rate limiters
-func (c *Client) waitForRateLimit() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	// Stop right away if the job was cancelled instead of reporting a rate limit error
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
 	if err := c.perMinuteLimiter.Wait(ctx); err != nil {
@@ -0,0 +1,36 @@
+package namecheap
+
+import (
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers/providertest"
+	"github.com/lum8rjack/redcompass/services/types"
+	"golang.org/x/time/rate"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunServiceTests(t, providertest.ServiceSuite{
+		Handler:  providertest.NamecheapHandler,
+		PageSize: pageSize,
+		New: func(t *testing.T, baseURL string) types.Service {
+			c, err := NewClient(`{"apiKey":"test-key","username":"test","ipAddress":"192.0.2.1","baseUrl":"` + baseURL + `"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.perMinuteLimiter = rate.NewLimiter(rate.Inf, 1)
+			c.perHourLimiter = rate.NewLimiter(rate.Inf, 1)
+			c.perDayLimiter = rate.NewLimiter(rate.Inf, 1)
+			return c
+		},
+	})
+}
+
+func TestSandbox(t *testing.T) {
+	c, err := NewClient(`{"apiKey":"test-key","username":"test","ipAddress":"192.0.2.1","sandbox":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.client.BaseURL != "https://api.sandbox.namecheap.com/xml.response" {
+		t.Fatalf("expected the sandbox URL, got %s", c.client.BaseURL)
+	}
+}
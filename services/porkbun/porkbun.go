@@ -3,24 +3,52 @@ package porkbun
 import (
 	"bytes"
 	"context"
+	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/lum8rjack/redcompass/providers"
 	"github.com/lum8rjack/redcompass/services/types"
 	"golang.org/x/time/rate"
 )
 
+// JSON schema of the settings shown on the Settings page
+//
+//go:embed settings.schema.json
+var settingsSchema []byte
+
+func init() {
+	providers.MustRegister(providers.Provider{
+		Name:     "Porkbun",
+		Kind:     providers.KindRegistrar,
+		Settings: settingsSchema,
+		NewService: func(settings string) (types.Service, error) {
+			return NewClient(settings)
+		},
+	})
+}
+
 type Settings struct {
 	ApiKey    string `json:"apiKey"`
 	SecretKey string `json:"secretKey"`
+	BaseURL   string `json:"baseUrl"`
 }
 
+// Default URL of the API, can be changed in the settings to use a proxy or a test server
+const defaultBaseURL = "https://api.porkbun.com/api/json/v3"
+
+// Maximum number of domains returned by the domain/listAll API
+const pageSize = 1000
+
 type Client struct {
 	client           *http.Client
+	baseURL          string
 	apiKey           string
 	secretKey        string
 	perMinuteLimiter *rate.Limiter
@@ -42,15 +70,16 @@ func NewClient(settings string) (*Client, error) {
 		return nil, errors.New("invalid settings")
 	}
 
-	// Create the web client
-	webclient := &http.Client{
-		Timeout: 10 * time.Second,
+	baseURL := defaultBaseURL
+	if porkbunSettings.BaseURL != "" {
+		baseURL = strings.TrimSuffix(porkbunSettings.BaseURL, "/")
 	}
 
 	// Porkbun doesn't document any specific rate limits but some other libraries use
 	// a 1.5 second delay between requests so we'll use that as a starting point.
 	return &Client{
-		client:           webclient,
+		client:           providers.NewHTTPClient("Porkbun", providers.ClientOptions{}),
+		baseURL:          baseURL,
 		apiKey:           porkbunSettings.ApiKey,
 		secretKey:        porkbunSettings.SecretKey,
 		perMinuteLimiter: rate.NewLimiter(rate.Every(time.Second*3/2), 1),
@@ -70,68 +99,56 @@ type PorkbunListAllRequest struct {
 }
 
 type DomainListResponse struct {
-	Status  string `json:"status"`
-	Domains []struct {
-		Domain       string `json:"domain"`
-		Status       string `json:"status"`
-		Tld          string `json:"tld"`
-		CreateDate   string `json:"createDate"`
-		ExpireDate   string `json:"expireDate"`
-		SecurityLock string `json:"securityLock"`
-		WhoisPrivacy string `json:"whoisPrivacy"`
-		AutoRenew    int    `json:"autoRenew"`
-		NotLocal     int    `json:"notLocal"`
-		Labels       []struct {
-			ID    string `json:"id"`
-			Title string `json:"title"`
-			Color string `json:"color"`
-		} `json:"labels"`
-	} `json:"domains"`
+	Status  string          `json:"status"`
+	Domains []PorkbunDomain `json:"domains"`
+}
+
+type PorkbunDomain struct {
+	Domain       string `json:"domain"`
+	Status       string `json:"status"`
+	Tld          string `json:"tld"`
+	CreateDate   string `json:"createDate"`
+	ExpireDate   string `json:"expireDate"`
+	SecurityLock string `json:"securityLock"`
+	WhoisPrivacy string `json:"whoisPrivacy"`
+	AutoRenew    int    `json:"autoRenew"`
+	NotLocal     int    `json:"notLocal"`
+	Labels       []struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+		Color string `json:"color"`
+	} `json:"labels"`
 }
 
 // GetDomains returns a list of all domains for the user
-func (c *Client) GetDomains() ([]types.Domain, error) {
+func (c *Client) GetDomains(ctx context.Context) ([]types.Domain, error) {
 	var domains []types.Domain
 
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(); err != nil {
-		return nil, err
-	}
-
-	requestBody := PorkbunListAllRequest{
-		SecretApiKey:  c.secretKey,
-		ApiKey:        c.apiKey,
-		Start:         "0",
-		IncludeLabels: "yes",
-	}
-	requestBodyBytes, err := json.Marshal(requestBody)
-	if err != nil {
-		return domains, err
-	}
-
-	reader := bytes.NewReader(requestBodyBytes)
-
-	resp, err := c.client.Post(
-		"https://api.porkbun.com/api/json/v3/domain/listAll",
-		"application/json",
-		reader,
-	)
-	if err != nil {
-		return domains, err
-	}
-	defer resp.Body.Close()
+	// Request every page, the API returns at most 1000 domains per page
+	var allDomains []PorkbunDomain
+	for start := 0; ; start += pageSize {
+		requestBody := PorkbunListAllRequest{
+			SecretApiKey:  c.secretKey,
+			ApiKey:        c.apiKey,
+			Start:         strconv.Itoa(start),
+			IncludeLabels: "yes",
+		}
 
-	var response DomainListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return domains, err
-	}
+		var response DomainListResponse
+		err := c.post(providers.Idempotent(ctx), c.baseURL+"/domain/listAll", requestBody, &response)
+		if err != nil {
+			return domains, err
+		}
 
-	if response.Status != "SUCCESS" {
-		return domains, errors.New(response.Status)
+		allDomains = append(allDomains, response.Domains...)
+		if len(response.Domains) < pageSize {
+			break
+		}
 	}
 
+	var err error
 	layout := "2006-01-02 15:04:05"
-	for _, x := range response.Domains {
+	for _, x := range allDomains {
 		newDomain := types.Domain{}
 
 		// Check the values
@@ -171,7 +188,7 @@ func (c *Client) GetDomains() ([]types.Domain, error) {
 		}
 
 		newDomain.IsOurDNS = true
-		usingPorkbunNameservers, err := c.isUsingPorkbunNameservers(x.Domain)
+		usingPorkbunNameservers, err := c.isUsingPorkbunNameservers(ctx, x.Domain)
 		if err != nil {
 			return domains, err
 		}
@@ -202,46 +219,21 @@ type PorkbunRetrieveRecordsResponse struct {
 }
 
 // GetDomainRecords returns a list of all records for a domain
-func (c *Client) GetDomainRecords(domain string) ([]types.Record, error) {
+func (c *Client) GetDomainRecords(ctx context.Context, domain string) ([]types.Record, error) {
 	var records []types.Record
 
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(); err != nil {
-		return records, err
-	}
-
 	requestBody := PorkbunRetrieveRecordsRequest{
 		SecretApiKey: c.secretKey,
 		ApiKey:       c.apiKey,
 	}
-	requestBodyBytes, err := json.Marshal(requestBody)
-	if err != nil {
-		return records, err
-	}
 
-	reader := bytes.NewReader(requestBodyBytes)
-
-	url := fmt.Sprintf("https://api.porkbun.com/api/json/v3/dns/retrieve/%s", domain)
-
-	resp, err := c.client.Post(
-		url,
-		"application/json",
-		reader,
-	)
-	if err != nil {
-		return records, err
-	}
-	defer resp.Body.Close()
+	url := fmt.Sprintf("%s/dns/retrieve/%s", c.baseURL, domain)
 
 	var response PorkbunRetrieveRecordsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	if err := c.post(providers.Idempotent(ctx), url, requestBody, &response); err != nil {
 		return records, err
 	}
 
-	if response.Status != "SUCCESS" {
-		return records, errors.New(response.Status)
-	}
-
 	for _, x := range response.Records {
 		r := types.Record{
 			Domain: domain,
@@ -268,6 +260,10 @@ func (c *Client) GetDomainRecords(domain string) ([]types.Record, error) {
 			r.HostId = x.ID
 		}
 
+		if x.Prio != "" && x.Prio != "0" {
+			r.Priority = x.Prio
+		}
+
 		records = append(records, r)
 	}
 	return records, nil
@@ -279,56 +275,194 @@ type PorkbunGetNsResponse struct {
 }
 
 // Check nameservers for a domain
-func (c *Client) isUsingPorkbunNameservers(domain string) (bool, error) {
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(); err != nil {
+func (c *Client) isUsingPorkbunNameservers(ctx context.Context, domain string) (bool, error) {
+	requestBody := PorkbunRetrieveRecordsRequest{
+		SecretApiKey: c.secretKey,
+		ApiKey:       c.apiKey,
+	}
+
+	url := fmt.Sprintf("%s/domain/getNs/%s", c.baseURL, domain)
+
+	var response PorkbunGetNsResponse
+	if err := c.post(providers.Idempotent(ctx), url, requestBody, &response); err != nil {
 		return false, err
 	}
 
+	if len(response.Nameservers) == 0 {
+		return false, nil
+	}
+
+	isPorkbunNameserver := strings.HasSuffix(response.Nameservers[0], ".porkbun.com")
+
+	return isPorkbunNameserver, nil
+}
+
+type PorkbunEditRecordRequest struct {
+	SecretApiKey string `json:"secretapikey"`
+	ApiKey       string `json:"apikey"`
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Content      string `json:"content"`
+	TTL          string `json:"ttl,omitempty"`
+	Prio         string `json:"prio,omitempty"`
+}
+
+type PorkbunCreateRecordResponse struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message"`
+	ID      json.Number `json:"id"`
+}
+
+type PorkbunStatusResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// CreateRecord adds a record to a domain
+func (c *Client) CreateRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	requestBody := c.newEditRecordRequest(record)
+	url := fmt.Sprintf("%s/dns/create/%s", c.baseURL, record.Domain)
+
+	var response PorkbunCreateRecordResponse
+	if err := c.post(ctx, url, requestBody, &response); err != nil {
+		return record, err
+	}
+
+	record.HostId = response.ID.String()
+	record.Name = fullRecordName(requestBody.Name, record.Domain)
+	return record, nil
+}
+
+// UpdateRecord edits the record with the matching HostId
+func (c *Client) UpdateRecord(ctx context.Context, record types.Record) (types.Record, error) {
+	if record.HostId == "" {
+		return record, errors.New("record id is empty")
+	}
+
+	requestBody := c.newEditRecordRequest(record)
+	url := fmt.Sprintf("%s/dns/edit/%s/%s", c.baseURL, record.Domain, record.HostId)
+
+	// Editing a record to the same values can safely be retried
+	var response PorkbunStatusResponse
+	if err := c.post(providers.Idempotent(ctx), url, requestBody, &response); err != nil {
+		return record, err
+	}
+
+	record.Name = fullRecordName(requestBody.Name, record.Domain)
+	return record, nil
+}
+
+// DeleteRecord deletes the record with the matching HostId
+func (c *Client) DeleteRecord(ctx context.Context, record types.Record) error {
+	if record.HostId == "" {
+		return errors.New("record id is empty")
+	}
+
 	requestBody := PorkbunRetrieveRecordsRequest{
 		SecretApiKey: c.secretKey,
 		ApiKey:       c.apiKey,
 	}
+	url := fmt.Sprintf("%s/dns/delete/%s/%s", c.baseURL, record.Domain, record.HostId)
+
+	var response PorkbunStatusResponse
+	return c.post(providers.Idempotent(ctx), url, requestBody, &response)
+}
+
+// Create the request body to create or edit a record. Porkbun returns the full
+// record name but expects only the subdomain when creating or editing a record.
+func (c *Client) newEditRecordRequest(record types.Record) PorkbunEditRecordRequest {
+	name := strings.TrimSuffix(record.Name, record.Domain)
+	name = strings.TrimSuffix(name, ".")
+
+	return PorkbunEditRecordRequest{
+		SecretApiKey: c.secretKey,
+		ApiKey:       c.apiKey,
+		Name:         name,
+		Type:         record.Type,
+		Content:      record.Address,
+		TTL:          record.TTL,
+		Prio:         record.Priority,
+	}
+}
+
+// Get the full record name the same way it is returned when retrieving records
+func fullRecordName(name string, domain string) string {
+	if name == "" {
+		return domain
+	}
+	return name + "." + domain
+}
+
+// Send a POST request to the API and decode the response
+func (c *Client) post(ctx context.Context, url string, requestBody any, response any) error {
+	// Wait for rate limiter
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return err
+	}
+
 	requestBodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	reader := bytes.NewReader(requestBodyBytes)
-
-	url := fmt.Sprintf("https://api.porkbun.com/api/json/v3/domain/getNs/%s", domain)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(requestBodyBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("content-type", "application/json")
 
-	resp, err := c.client.Post(
-		url,
-		"application/json",
-		reader,
-	)
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return false, err
+		return providers.FromRequestError("Porkbun", err)
 	}
 	defer resp.Body.Close()
 
-	var response PorkbunGetNsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return false, err
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return providers.FromRequestError("Porkbun", err)
 	}
 
-	if response.Status != "SUCCESS" {
-		return false, errors.New(response.Status)
+	// Every response has a status and a message when the request failed
+	var status PorkbunStatusResponse
+	if err := json.Unmarshal(body, &status); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return providers.FromResponse("Porkbun", resp, "")
+		}
+		return err
 	}
 
-	if len(response.Nameservers) == 0 {
-		return false, nil
+	if status.Status != "SUCCESS" {
+		return apiError(resp, status.Message)
 	}
 
-	isPorkbunNameserver := strings.HasSuffix(response.Nameservers[0], ".porkbun.com")
+	return json.Unmarshal(body, response)
+}
 
-	return isPorkbunNameserver, nil
+// Classify a failed response using the message since Porkbun returns most errors with a 400
+func apiError(resp *http.Response, message string) error {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "invalid api key") || strings.Contains(lower, "all api access is disabled"):
+		return providers.NewError("Porkbun", providers.ErrAuth, message)
+	case strings.Contains(lower, "rate limit") || strings.Contains(lower, "too many"):
+		return providers.RateLimited("Porkbun", providers.ParseRetryAfter(resp.Header.Get("Retry-After")), message)
+	case strings.Contains(lower, "invalid domain") || strings.Contains(lower, "not found"):
+		return providers.NewError("Porkbun", providers.ErrNotFound, message)
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest:
+		return providers.FromResponse("Porkbun", resp, message)
+	}
+
+	return errors.New(message)
 }
 
 // Wait for the rate limiters
-func (c *Client) waitForRateLimit() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	// Stop right away if the job was cancelled instead of reporting a rate limit error
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
 	if err := c.perMinuteLimiter.Wait(ctx); err != nil {
@@ -0,0 +1,24 @@
+package porkbun
+
+import (
+	"testing"
+
+	"github.com/lum8rjack/redcompass/providers/providertest"
+	"github.com/lum8rjack/redcompass/services/types"
+	"golang.org/x/time/rate"
+)
+
+func TestConformance(t *testing.T) {
+	providertest.RunServiceTests(t, providertest.ServiceSuite{
+		Handler:  providertest.PorkbunHandler,
+		PageSize: pageSize,
+		New: func(t *testing.T, baseURL string) types.Service {
+			c, err := NewClient(`{"apiKey":"pk1_test","secretKey":"sk1_test","baseUrl":"` + baseURL + `"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.perMinuteLimiter = rate.NewLimiter(rate.Inf, 1)
+			return c
+		},
+	})
+}
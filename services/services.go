@@ -3,21 +3,21 @@ package services
 import (
 	"errors"
 
-	"github.com/lum8rjack/redcompass/services/namecheap"
-	"github.com/lum8rjack/redcompass/services/porkbun"
+	"github.com/lum8rjack/redcompass/providers"
 	"github.com/lum8rjack/redcompass/services/types"
+
+	// Registrars register themselves with the providers registry
+	_ "github.com/lum8rjack/redcompass/services/cloudflare"
+	_ "github.com/lum8rjack/redcompass/services/namecheap"
+	_ "github.com/lum8rjack/redcompass/services/porkbun"
 )
 
+// Create the client for a registrar from the providers registry
 func NewService(provider string, settings string) (types.Service, error) {
-
-	switch provider {
-	case "Namecheap":
-		return namecheap.NewClient(settings)
-	case "Porkbun":
-		return porkbun.NewClient(settings)
-	case "Cloudflare":
-		return nil, errors.New("service provider Cloudflare is not supported yet")
+	p, ok := providers.Lookup(provider)
+	if !ok || p.Kind != providers.KindRegistrar {
+		return nil, errors.New("invalid provider")
 	}
 
-	return nil, errors.New("invalid provider")
+	return p.NewService(settings)
 }
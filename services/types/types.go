@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Domain struct {
 	Name       string
@@ -14,16 +17,27 @@ type Domain struct {
 }
 
 type Record struct {
-	Domain  string
-	HostId  string
-	Name    string
-	Type    string
-	Address string
-	TTL     string
+	Domain   string
+	HostId   string
+	Name     string
+	Type     string
+	Address  string
+	TTL      string
+	Priority string
+	Proxied  bool
 }
 
 type Service interface {
 	GetName() string
-	GetDomains() ([]Domain, error)
-	GetDomainRecords(domain string) ([]Record, error)
+	GetDomains(ctx context.Context) ([]Domain, error)
+	GetDomainRecords(ctx context.Context, domain string) ([]Record, error)
+	CreateRecord(ctx context.Context, record Record) (Record, error)
+	UpdateRecord(ctx context.Context, record Record) (Record, error)
+	DeleteRecord(ctx context.Context, record Record) error
+}
+
+// DomainTotaler is implemented by services whose API reports the total number
+// of domains in the account, which is used to detect a truncated sync
+type DomainTotaler interface {
+	GetTotalDomains() int
 }